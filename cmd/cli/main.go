@@ -2,13 +2,32 @@ package main
 
 import (
 	"flag"
+	"github.com/dkoshkin/invoices-validator/pkg/ack"
 	"github.com/dkoshkin/invoices-validator/pkg/controller"
+	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
 	// setup logging
 	logLevel := flag.String("log-level", "info", "log level")
+	sourceName := flag.String("source", "", "storage source to validate, one of \"dropbox\", \"local\", \"drive\" or \"s3\" (defaults to $SOURCE or \"dropbox\")")
+	path := flag.String("path", "", "root folder to validate, Google Drive folder ID or S3 key prefix (defaults to $DROPBOX_PATH, $LOCAL_PATH, $GOOGLE_DRIVE_FOLDER_ID or $S3_PREFIX)")
+	contentChecks := flag.Bool("content-checks", false, "download .docx and .pdf invoices and validate their content matches the file and folder names")
+	report := flag.String("report", "", "write a monthly summary of the invoice totals per client, one of \"csv\" or \"json\"")
+	reportFile := flag.String("report-file", "", "file to write the report to (defaults to stdout)")
+	full := flag.Bool("full", false, "validate the whole tree instead of only the changes since the previous run")
+	stateStore := flag.String("state-store", "", "where to keep state between runs, one of \"file\", \"bolt\" or \"dynamodb\" (defaults to $STATE_STORE or \"file\" when a state file is set)")
+	stateFile := flag.String("state-file", "", "file to keep state in between runs, enables validating only the changes (defaults to $STATE_FILE)")
+	notifyOnlyOnNew := flag.Bool("notify-only-on-new", false, "only send notifications when there are errors that were not reported by the previous run, needs a state store")
+	acknowledge := flag.String("ack", "", "acknowledge the error with the fingerprint, ie \"file-name:/invoices/john doe/legacy.docx\", so that it is no longer notified about and exit")
+	ackReason := flag.String("ack-reason", "", "why the error passed to -ack is expected")
+	ackExpires := flag.String("ack-expires", "", "last day the error passed to -ack is acknowledged, ie \"2019-12-31\" (defaults to never)")
+	fix := flag.Bool("fix", false, "output the plan of the proposed renames of the files and folders with naming errors instead of notifying")
+	plan := flag.String("plan", "", "format of the plan of proposed renames, one of \"table\", \"json\" or \"email\", implies -fix (defaults to \"table\")")
+	planFile := flag.String("plan-file", "", "file to write the table or JSON plan to (defaults to stdout)")
+	apply := flag.Bool("apply", false, "rename the files and folders with naming errors instead of notifying, never overwrites existing files")
+	applyIDs := flag.String("apply-ids", "", "comma separated IDs of the renames of a reviewed plan to apply")
 	flag.Parse()
 
 	level, err := log.ParseLevel(*logLevel)
@@ -18,5 +37,35 @@ func main() {
 		log.SetLevel(level)
 	}
 
-	controller.Run()
+	opts := controller.Options{
+		Source:          *sourceName,
+		Path:            *path,
+		ContentChecks:   *contentChecks,
+		Report:          *report,
+		ReportFile:      *reportFile,
+		Full:            *full,
+		StateStore:      *stateStore,
+		StateFile:       *stateFile,
+		Fix:             *fix,
+		Plan:            *plan,
+		PlanFile:        *planFile,
+		Apply:           *apply,
+		ApplyIDs:        stringsx.Split(*applyIDs, ","),
+		NotifyOnlyOnNew: *notifyOnlyOnNew,
+	}
+	if *acknowledge != "" {
+		a := ack.Ack{
+			Fingerprint: *acknowledge,
+			Expires:     *ackExpires,
+			Reason:      *ackReason,
+		}
+		if err := controller.Acknowledge(opts, a); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := controller.Run(opts); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -8,7 +8,7 @@ import (
 )
 
 func HandleRequest() error {
-	return controller.Run()
+	return controller.Run(controller.Options{})
 }
 
 const logLevelEnv = "LOG_LEVEL"
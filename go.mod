@@ -1,16 +1,31 @@
 module github.com/dkoshkin/invoices-validator
 
+go 1.21
+
 require (
 	github.com/aws/aws-lambda-go v1.8.1
+	github.com/aws/aws-sdk-go v1.16.26
 	github.com/davecgh/go-spew v1.1.1
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
 	github.com/dropbox/dropbox-sdk-go-unofficial v5.4.0+incompatible
-	github.com/gorilla/schema v1.0.2 // indirect
-	github.com/sendgrid/rest v2.4.1+incompatible // indirect
+	github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
 	github.com/sendgrid/sendgrid-go v3.4.1+incompatible
 	github.com/sfreiberg/gotwilio v0.0.0-20181223013140-ccf5c3cb3e06
 	github.com/sirupsen/logrus v1.3.0
 	github.com/thoas/go-funk v0.0.0-20181020164546-fbae87fb5b5c
-	golang.org/x/net v0.0.0-20190125091013-d26f9f9a57f3 // indirect
-	golang.org/x/oauth2 v0.0.0-20190115181402-5dab4167f31c // indirect
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/net v0.0.0-20190125091013-d26f9f9a57f3
+	golang.org/x/oauth2 v0.0.0-20190115181402-5dab4167f31c
+	gopkg.in/yaml.v2 v2.2.2
+)
+
+require (
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
+	github.com/golang/protobuf v1.2.0 // indirect
+	github.com/gorilla/schema v1.0.2 // indirect
+	github.com/jmespath/go-jmespath v0.0.0-20180206201540-c2b33e8439af // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
+	github.com/sendgrid/rest v2.4.1+incompatible // indirect
+	golang.org/x/crypto v0.0.0-20180904163835-0709b304e793 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	google.golang.org/appengine v1.4.0 // indirect
 )
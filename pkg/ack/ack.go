@@ -0,0 +1,169 @@
+package ack
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/state"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	acksFileEnv = "ACKS_FILE"
+
+	// ExpiresLayout is the format of the expiry dates, ie "2019-12-31"
+	ExpiresLayout = "2006-01-02"
+
+	// storeKey is the key of the acknowledgements in the state store
+	storeKey = "acks"
+)
+
+// Ack acknowledges an error so that it is no longer notified about
+type Ack struct {
+	// Fingerprint of the acknowledged error, ie "file-name:/invoices/john doe/legacy.docx"
+	Fingerprint string `yaml:"fingerprint" json:"fingerprint"`
+	// Expires is the last day the error is acknowledged, in the ExpiresLayout format, never expires when empty
+	Expires string `yaml:"expires,omitempty" json:"expires,omitempty"`
+	Reason  string `yaml:"reason,omitempty" json:"reason,omitempty"`
+}
+
+type Acks struct {
+	Acks []Ack `yaml:"acks" json:"acks"`
+}
+
+// Validate returns an error if an ack has no fingerprint or an invalid expiry date
+func (a Ack) Validate() error {
+	if a.Fingerprint == "" {
+		return fmt.Errorf("fingerprint must be set")
+	}
+	if a.Expires != "" {
+		if _, err := time.Parse(ExpiresLayout, a.Expires); err != nil {
+			return fmt.Errorf("expires of %q must be a date in the %q format: %v", a.Fingerprint, ExpiresLayout, err)
+		}
+	}
+
+	return nil
+}
+
+// Expired returns true if the ack expired before now
+func (a Ack) Expired(now time.Time) bool {
+	if a.Expires == "" {
+		return false
+	}
+	// already validated
+	expires, _ := time.Parse(ExpiresLayout, a.Expires)
+	return !now.Before(expires.AddDate(0, 0, 1))
+}
+
+// ConfiguredAcks returns the acks of ACKS_FILE and the state store, store may be nil
+func ConfiguredAcks(store state.Store) (*Acks, error) {
+	acks := &Acks{}
+
+	if file := os.Getenv(acksFileEnv); file != "" {
+		log.Infof("Using acknowledgements file: %q", file)
+		fromFile, err := Load(file)
+		if err != nil {
+			return nil, err
+		}
+		acks.Acks = append(acks.Acks, fromFile.Acks...)
+	}
+
+	if store != nil {
+		fromStore, err := loadStore(store)
+		if err != nil {
+			return nil, err
+		}
+		acks.Acks = append(acks.Acks, fromStore.Acks...)
+	}
+
+	return acks, nil
+}
+
+// Load reads the acks of a YAML or JSON file
+func Load(file string) (*Acks, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read acknowledgements file: %v", err)
+	}
+
+	acks := &Acks{}
+	if err := yaml.UnmarshalStrict(data, acks); err != nil {
+		return nil, fmt.Errorf("could not parse acknowledgements file %q: %v", file, err)
+	}
+	for _, a := range acks.Acks {
+		if err := a.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid acknowledgements file %q: %v", file, err)
+		}
+	}
+
+	return acks, nil
+}
+
+// Add saves the ack in the state store, replacing an ack of the same fingerprint and removing expired acks
+func Add(store state.Store, a Ack, now time.Time) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	acks, err := loadStore(store)
+	if err != nil {
+		return err
+	}
+
+	updated := &Acks{}
+	for _, existing := range acks.Acks {
+		if existing.Expired(now) || strings.EqualFold(existing.Fingerprint, a.Fingerprint) {
+			continue
+		}
+		updated.Acks = append(updated.Acks, existing)
+	}
+	updated.Acks = append(updated.Acks, a)
+
+	data, err := json.Marshal(updated)
+	if err != nil {
+		return fmt.Errorf("could not encode acknowledgements: %v", err)
+	}
+	if err := store.Put(storeKey, data); err != nil {
+		return fmt.Errorf("could not save acknowledgements: %v", err)
+	}
+
+	return nil
+}
+
+func loadStore(store state.Store) (*Acks, error) {
+	acks := &Acks{}
+
+	data, err := store.Get(storeKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not read acknowledgements: %v", err)
+	}
+	if data == nil {
+		return acks, nil
+	}
+	if err := json.Unmarshal(data, acks); err != nil {
+		return nil, fmt.Errorf("could not decode acknowledgements: %v", err)
+	}
+
+	return acks, nil
+}
+
+// Apply marks the errors matching an ack that did not expire as acknowledged and all other errors as not acknowledged
+func (acks *Acks) Apply(errs []validator.ValidationError, now time.Time) {
+	for i := range errs {
+		errs[i].Acknowledged = false
+		errs[i].AckReason = ""
+		for _, a := range acks.Acks {
+			if a.Expired(now) || !strings.EqualFold(a.Fingerprint, errs[i].Fingerprint()) {
+				continue
+			}
+			errs[i].Acknowledged = true
+			errs[i].AckReason = a.Reason
+			break
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package awsx
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// NewSession returns an AWS session with the overrides of config.
+// Credentials and region are read using the default AWS SDK chain, including the profiles of ~/.aws/config
+// which are otherwise only read when AWS_SDK_LOAD_CONFIG is set
+func NewSession(config *aws.Config) (*session.Session, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create AWS session: %v", err)
+	}
+
+	return sess, nil
+}
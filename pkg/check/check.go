@@ -2,103 +2,119 @@ package check
 
 import (
 	"fmt"
-	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
-	"path/filepath"
-	"regexp"
-	"strings"
-
-	"github.com/thoas/go-funk"
 
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
 	"github.com/dkoshkin/invoices-validator/pkg/validator"
-	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/files"
 
 	log "github.com/sirupsen/logrus"
 )
 
-const (
-	// Do not allow ',' as that likely means its Last, First name
-	folderNameRegex    = "^[^,]*$"
-	folderNameExpected = "First Last, ie John Doe"
-
-	fileNameRegex    = `^(0[1-9]|1[0-2])(0[1-9]|1\d|2\d|3[01])(1|2)\d{1}-\d{2}\.docx$`
-	fileNameExpected = "Something like \"013119-01.docx\""
-)
-
 type Checker interface {
 	Check(validator validator.Validator)
 }
 
+// AggregateChecker validates entries that depend on each other,
+// entries are observed while listing and checked once the listing completes
+type AggregateChecker interface {
+	Observe(entry source.Entry)
+	Check(validator *validator.Validator)
+}
+
+// NameRules returns the names of the rules of the errors reported by FolderCheck and FileCheck
+func NameRules(rs *rules.RuleSet) []string {
+	names := []string{dateRule}
+	for _, r := range rs.Rules {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
 type FolderCheck struct {
-	Folder          *files.FolderMetadata
-	FoldersToIgnore []string
+	Folder *source.Entry
+	Rules  *rules.RuleSet
+	Ignore *ignore.Matcher
 }
 
 func (c *FolderCheck) Check(validator *validator.Validator) {
 	name := c.Folder.Name
 
 	// skip processing certain folders
-	folders := stringsx.Split(filepath.Dir(c.Folder.PathLower), string(filepath.Separator))
-	if len(funk.IntersectString(c.FoldersToIgnore, folders)) > 0 || funk.ContainsString(c.FoldersToIgnore, strings.ToLower(name)) {
-		log.Debug("Ignoring Folder %q", name)
+	if c.Ignore.Folder(c.Folder.PathDisplay) {
+		log.Debugf("Ignoring Folder %q", name)
 		return
 	}
 
-	log.Debug("Found Folder: %q", name)
+	log.Debugf("Found Folder: %q", name)
 
-	check := nameValidator{
-		name:           name,
-		regex:          folderNameRegex,
-		expected:       folderNameExpected,
-		additionalInfo: fmt.Sprintf("Folder: %q", c.Folder.PathDisplay),
+	for _, rule := range c.Rules.For(*c.Folder) {
+		check := nameValidator{
+			name:           name,
+			path:           c.Folder.PathDisplay,
+			rule:           rule,
+			additionalInfo: fmt.Sprintf("Folder: %q", c.Folder.PathDisplay),
+		}
+		validator.Validate(check)
 	}
-	validator.Validate(check)
 }
 
 type FileCheck struct {
-	File            *files.FileMetadata
-	FoldersToIgnore []string
-	FilesToIgnore   []string
+	File   *source.Entry
+	Rules  *rules.RuleSet
+	Dates  DateOptions
+	Ignore *ignore.Matcher
 }
 
 func (c *FileCheck) Check(validator *validator.Validator) {
 	name := c.File.Name
 
 	// skip processing certain files and files in skipped folders
-	folders := stringsx.Split(filepath.Dir(c.File.PathLower), string(filepath.Separator))
-
-	// if any parent Folder is in FOLDERS_TO_IGNORE OR File in FILES_TO_IGNORE, skip
-	if len(funk.IntersectString(c.FoldersToIgnore, folders)) > 0 || funk.ContainsString(c.FilesToIgnore, name) {
-		log.Debug("Ignoring File %q", c.File.PathDisplay)
+	if c.Ignore.File(c.File.PathDisplay) {
+		log.Debugf("Ignoring File %q", c.File.PathDisplay)
 		return
 	}
 
-	log.Debug("Found File: %q", c.File.PathDisplay)
+	log.Debugf("Found File: %q", c.File.PathDisplay)
 
-	check := nameValidator{
-		name:           name,
-		regex:          fileNameRegex,
-		expected:       fileNameExpected,
-		additionalInfo: fmt.Sprintf("File: %q", c.File.PathDisplay),
+	for _, rule := range c.Rules.For(*c.File) {
+		check := nameValidator{
+			name:           name,
+			path:           c.File.PathDisplay,
+			rule:           rule,
+			additionalInfo: fmt.Sprintf("File: %q", c.File.PathDisplay),
+		}
+		validator.Validate(check)
+
+		dateCheck := dateValidator{
+			name:           name,
+			path:           c.File.PathDisplay,
+			rule:           rule,
+			options:        c.Dates,
+			additionalInfo: fmt.Sprintf("File: %q", c.File.PathDisplay),
+		}
+		validator.Validate(dateCheck)
 	}
-	validator.Validate(check)
 }
 
 type nameValidator struct {
 	name           string
-	regex          string
-	expected       string
+	path           string
+	rule           *rules.Rule
 	additionalInfo string
 }
 
 func (nv nameValidator) Validate() (bool, []validator.ValidationError) {
 	v := validator.NewValidator()
 
-	match, _ := regexp.MatchString(nv.regex, nv.name)
-	if !match {
+	if !nv.rule.MatchString(nv.name) {
 		err := validator.ValidationError{
 			Actual:         fmt.Sprintf("%q", nv.name),
-			Expected:       nv.expected,
+			Expected:       nv.rule.Expected,
 			AdditionalInfo: nv.additionalInfo,
+			Severity:       nv.rule.Severity,
+			Rule:           nv.rule.Name,
+			Path:           nv.path,
 		}
 		v.AddError(err)
 	}
@@ -0,0 +1,183 @@
+package check
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/document"
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	contentUnreadableRule = "content-unreadable"
+	contentDateRule       = "content-date"
+	contentClientRule     = "content-client"
+)
+
+// ContentRules are the names of the rules of the errors reported by ContentCheck
+var ContentRules = []string{contentUnreadableRule, contentDateRule, contentClientRule}
+
+var whitespaceRegex = regexp.MustCompile(`\s+`)
+
+// ContentCheck downloads a .docx or .pdf invoice and validates its text matches the date in the file name
+// and the client name of the parent folder
+type ContentCheck struct {
+	File       *source.Entry
+	Rules      *rules.RuleSet
+	Downloader source.Downloader
+	Ignore     *ignore.Matcher
+}
+
+func (c *ContentCheck) Check(validator *validator.Validator) {
+	if !document.Supported(c.File.Name) || c.Ignore.File(c.File.PathDisplay) {
+		return
+	}
+
+	// the content can only be checked for files with a valid date in the name, other files are already reported
+	date, ok := invoiceDate(c.Rules, c.File)
+	if !ok {
+		return
+	}
+
+	log.Debugf("Checking content of File: %q", c.File.PathDisplay)
+
+	content, err := c.Downloader.Download(*c.File)
+	if err == source.ErrNotDownloadable {
+		log.Debugf("Skipping content check of File %q: %v", c.File.PathDisplay, err)
+		return
+	}
+	if err != nil {
+		log.Errorf("could not check content: %v", err)
+		return
+	}
+	defer content.Close()
+
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		log.Errorf("could not check content: could not read %q: %v", c.File.PathDisplay, err)
+		return
+	}
+
+	check := contentValidator{
+		file:   c.File,
+		data:   data,
+		date:   date,
+		client: clientName(c.File),
+	}
+	validator.Validate(check)
+}
+
+type contentValidator struct {
+	file   *source.Entry
+	data   []byte
+	date   time.Time
+	client string
+}
+
+func (cv contentValidator) Validate() (bool, []validator.ValidationError) {
+	v := validator.NewValidator()
+
+	newError := func(rule string, expected string) validator.ValidationError {
+		return validator.ValidationError{
+			Actual:         fmt.Sprintf("%q", cv.file.Name),
+			Expected:       expected,
+			AdditionalInfo: fmt.Sprintf("File: %q", cv.file.PathDisplay),
+			Severity:       rules.SeverityError,
+			Rule:           rule,
+			Path:           cv.file.PathDisplay,
+		}
+	}
+
+	doc, err := document.Parse(cv.file.Name, cv.data)
+	if err != nil {
+		e := newError(contentUnreadableRule, "A valid Word or PDF document")
+		e.AdditionalInfo = fmt.Sprintf("%s, %v", e.AdditionalInfo, err)
+		v.AddError(e)
+		return v.Valid()
+	}
+	text := normalize(doc.Text())
+
+	found := false
+	for _, format := range dateFormats(cv.date) {
+		if strings.Contains(text, normalize(format)) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		v.AddError(newError(contentDateRule,
+			fmt.Sprintf("Invoice content dated %s, same as the file name", cv.date.Format(dateDisplayLayout))))
+	}
+
+	if cv.client != "" && !strings.Contains(text, normalize(cv.client)) {
+		v.AddError(newError(contentClientRule,
+			fmt.Sprintf("Invoice content for client %q, same as the folder name", cv.client)))
+	}
+
+	return v.Valid()
+}
+
+// invoiceDate returns the date parsed from the file name by the first rule capturing one
+func invoiceDate(rs *rules.RuleSet, file *source.Entry) (time.Time, bool) {
+	for _, rule := range rs.For(*file) {
+		raw, layout, ok := rule.Date(file.Name)
+		if !ok {
+			continue
+		}
+		date, err := time.Parse(layout, raw)
+		if err != nil {
+			continue
+		}
+		return date, true
+	}
+
+	return time.Time{}, false
+}
+
+// clientName returns the name of the client folder containing the file, skipping year folders
+func clientName(file *source.Entry) string {
+	dir := path.Dir(file.PathDisplay)
+	if match, _ := regexp.MatchString(yearFolderRegex, path.Base(dir)); match {
+		dir = path.Dir(dir)
+	}
+	if dir == "/" {
+		return ""
+	}
+
+	return path.Base(dir)
+}
+
+// dateFormats returns the common ways an invoice date is written
+func dateFormats(date time.Time) []string {
+	layouts := []string{
+		"01/02/06",
+		"01/02/2006",
+		"1/2/06",
+		"1/2/2006",
+		"01-02-2006",
+		"01.02.2006",
+		"2006-01-02",
+		"January 2, 2006",
+		"Jan 2, 2006",
+		"2 January 2006",
+	}
+
+	formats := make([]string, 0, len(layouts))
+	for _, layout := range layouts {
+		formats = append(formats, date.Format(layout))
+	}
+
+	return formats
+}
+
+// normalize lowercases the text and collapses all whitespace to make comparisons more lenient
+func normalize(s string) string {
+	return whitespaceRegex.ReplaceAllString(strings.ToLower(strings.TrimSpace(s)), " ")
+}
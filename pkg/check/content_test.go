@@ -0,0 +1,142 @@
+package check
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeDownloader returns the content of the files keyed by their ID
+type fakeDownloader map[string][]byte
+
+func (d fakeDownloader) Download(entry source.Entry) (io.ReadCloser, error) {
+	data, ok := d[entry.ID]
+	if !ok {
+		return nil, errors.New("download failed")
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// buildDocx returns a word processing document with a paragraph for every line
+func buildDocx(t *testing.T, lines ...string) []byte {
+	var body strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&body, "<w:p><w:r><w:t>%s</w:t></w:r></w:p>", line)
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	part, err := archive.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(part, `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s</w:body></w:document>`, body.String())
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestContentCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		content   []byte
+		wantRules []string
+	}{
+		{
+			name:    "matching invoice",
+			path:    "/Invoices/John Doe/013119-01.docx",
+			content: buildDocx(t, "Invoice", "Bill to: John Doe", "Date: January 31, 2019"),
+		},
+		{
+			name:    "matching invoice in a year folder",
+			path:    "/Invoices/John Doe/2019/013119-01.docx",
+			content: buildDocx(t, "John Doe", "01/31/2019"),
+		},
+		{
+			name:      "date mismatch",
+			path:      "/Invoices/John Doe/013119-01.docx",
+			content:   buildDocx(t, "John Doe", "02/01/2019"),
+			wantRules: []string{contentDateRule},
+		},
+		{
+			name:      "client mismatch",
+			path:      "/Invoices/John Doe/013119-01.docx",
+			content:   buildDocx(t, "Jane Doe", "01/31/2019"),
+			wantRules: []string{contentClientRule},
+		},
+		{
+			name: "download error",
+			path: "/Invoices/John Doe/013119-01.docx",
+		},
+		{
+			name:      "unparsable document",
+			path:      "/Invoices/John Doe/013119-01.docx",
+			content:   []byte("not a document"),
+			wantRules: []string{contentUnreadableRule},
+		},
+		{
+			// the name is already reported by the file check
+			name:    "invalid name",
+			path:    "/Invoices/John Doe/1-31-19.docx",
+			content: []byte("not a document"),
+		},
+		{
+			name:    "unsupported extension",
+			path:    "/Invoices/John Doe/013119-01.txt",
+			content: []byte("not a document"),
+		},
+	}
+
+	ignored, err := ignore.NewMatcher(nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &source.Entry{
+				ID:          tt.path,
+				Name:        tt.path[strings.LastIndex(tt.path, "/")+1:],
+				PathLower:   strings.ToLower(tt.path),
+				PathDisplay: tt.path,
+			}
+			downloader := fakeDownloader{}
+			if tt.content != nil {
+				downloader[file.ID] = tt.content
+			}
+
+			v := validator.NewValidator()
+			c := ContentCheck{
+				File:       file,
+				Rules:      rules.Default(),
+				Downloader: downloader,
+				Ignore:     ignored,
+			}
+			c.Check(v)
+
+			_, errs := v.Valid()
+			var gotRules []string
+			for _, e := range errs {
+				gotRules = append(gotRules, e.Rule)
+				if e.Path != tt.path {
+					t.Errorf("expected path %q, got %q", tt.path, e.Path)
+				}
+			}
+			if !reflect.DeepEqual(gotRules, tt.wantRules) {
+				t.Errorf("expected rules %v, got %v", tt.wantRules, gotRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package check
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"time"
+)
+
+const (
+	dateRule = "file-date"
+
+	dateDisplayLayout = "January 2, 2006"
+)
+
+// DateOptions configure the validation of the invoice dates parsed from the file names
+type DateOptions struct {
+	// NotBefore flags invoices dated before it, ignored when zero
+	NotBefore time.Time
+	// Now flags invoices dated after it, ignored when zero
+	Now time.Time
+}
+
+// dateValidator checks the date captured by a rule is a real calendar date within the allowed range
+type dateValidator struct {
+	name           string
+	path           string
+	rule           *rules.Rule
+	options        DateOptions
+	additionalInfo string
+}
+
+func (dv dateValidator) Validate() (bool, []validator.ValidationError) {
+	v := validator.NewValidator()
+
+	raw, layout, ok := dv.rule.Date(dv.name)
+	if !ok {
+		return v.Valid()
+	}
+
+	newError := func(expected string, info string) validator.ValidationError {
+		return validator.ValidationError{
+			Actual:         fmt.Sprintf("%q", dv.name),
+			Expected:       expected,
+			AdditionalInfo: fmt.Sprintf("%s, %s", dv.additionalInfo, info),
+			Severity:       dv.rule.Severity,
+			Rule:           dateRule,
+			Path:           dv.path,
+		}
+	}
+
+	date, err := time.Parse(layout, raw)
+	if err != nil {
+		v.AddError(newError(
+			fmt.Sprintf("A valid calendar date in the %q format", layout),
+			fmt.Sprintf("could not parse date %q", raw)))
+		return v.Valid()
+	}
+
+	parsed := fmt.Sprintf("parsed date %s", date.Format(dateDisplayLayout))
+	if !dv.options.Now.IsZero() {
+		// compare calendar days only, invoices dated today are fine
+		year, month, day := dv.options.Now.Date()
+		today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		if date.After(today) {
+			v.AddError(newError("An invoice date that is not in the future", parsed))
+		}
+	}
+	if !dv.options.NotBefore.IsZero() && date.Before(dv.options.NotBefore) {
+		v.AddError(newError(
+			fmt.Sprintf("An invoice date on or after %s", dv.options.NotBefore.Format(dateDisplayLayout)),
+			parsed))
+	}
+
+	return v.Valid()
+}
@@ -0,0 +1,55 @@
+package check
+
+import (
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDateValidator(t *testing.T) {
+	rs := rules.Default()
+	fileRule := rs.Rules[1]
+	now := time.Date(2019, 2, 1, 18, 30, 0, 0, time.Local)
+	cutoff := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		file    string
+		options DateOptions
+		want    []string
+	}{
+		{name: "valid date", file: "013119-01.docx", options: DateOptions{Now: now, NotBefore: cutoff}},
+		{name: "no options", file: "123199-01.docx"},
+		{name: "not a calendar date", file: "023019-01.docx", want: []string{`A valid calendar date in the "010206" format`}},
+		{name: "dated today", file: "020119-01.docx", options: DateOptions{Now: now}},
+		{name: "dated tomorrow", file: "020219-01.docx", options: DateOptions{Now: now}, want: []string{"An invoice date that is not in the future"}},
+		{name: "dated on the cutoff", file: "010119-01.docx", options: DateOptions{NotBefore: cutoff}},
+		{name: "dated before the cutoff", file: "123118-01.docx", options: DateOptions{NotBefore: cutoff}, want: []string{"An invoice date on or after January 1, 2019"}},
+		// the name is already reported by the name rule
+		{name: "invalid name", file: "1-31-19.docx", options: DateOptions{Now: now, NotBefore: cutoff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := dateValidator{
+				name:    tt.file,
+				path:    "/Invoices/John Doe/" + tt.file,
+				rule:    fileRule,
+				options: tt.options,
+			}
+			_, errs := check.Validate()
+
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Expected)
+				if e.Rule != dateRule || e.Path != check.path {
+					t.Errorf("unexpected error: %+v", e)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
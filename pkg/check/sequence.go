@@ -0,0 +1,140 @@
+package check
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	sequenceRule     = "file-sequence"
+	sequenceExpected = "Invoices of the same day numbered consecutively starting at 01"
+)
+
+// SequenceRules are the names of the rules of the errors reported by SequenceCheck
+var SequenceRules = []string{sequenceRule}
+
+// SequenceCheck validates the per-day invoice numbers, ie "013119-01.docx", "013119-02.docx",
+// of all files in the same folder
+type SequenceCheck struct {
+	Rules  *rules.RuleSet
+	Ignore *ignore.Matcher
+
+	sequences map[sequenceKey]*sequence
+}
+
+type sequenceKey struct {
+	folder string
+	date   string
+}
+
+type sequence struct {
+	folder   string
+	date     string
+	severity string
+	// files keyed by their invoice number
+	files map[int][]string
+}
+
+func (c *SequenceCheck) Observe(entry source.Entry) {
+	if entry.IsFolder || c.Ignore.File(entry.PathDisplay) {
+		return
+	}
+
+	for _, rule := range c.Rules.For(entry) {
+		date, seq, ok := rule.Sequence(entry.Name)
+		if !ok {
+			continue
+		}
+
+		if c.sequences == nil {
+			c.sequences = make(map[sequenceKey]*sequence)
+		}
+		key := sequenceKey{folder: path.Dir(entry.PathLower), date: date}
+		s, ok := c.sequences[key]
+		if !ok {
+			s = &sequence{
+				folder:   path.Dir(entry.PathDisplay),
+				date:     date,
+				severity: rule.Severity,
+				files:    make(map[int][]string),
+			}
+			c.sequences[key] = s
+		}
+		s.files[seq] = append(s.files[seq], entry.Name)
+		return
+	}
+}
+
+func (c *SequenceCheck) Check(validator *validator.Validator) {
+	keys := make([]sequenceKey, 0, len(c.sequences))
+	for key := range c.sequences {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].folder != keys[j].folder {
+			return keys[i].folder < keys[j].folder
+		}
+		return keys[i].date < keys[j].date
+	})
+
+	for _, key := range keys {
+		validator.Validate(c.sequences[key])
+	}
+}
+
+func (s *sequence) Validate() (bool, []validator.ValidationError) {
+	v := validator.NewValidator()
+
+	numbers := make([]int, 0, len(s.files))
+	var names []string
+	for n, files := range s.files {
+		numbers = append(numbers, n)
+		names = append(names, files...)
+	}
+	sort.Ints(numbers)
+	sort.Strings(names)
+
+	var problems []string
+	for _, n := range numbers {
+		// the same invoice saved with different extensions, ie a .docx and its exported .pdf, is not a duplicate
+		stems := make(map[string]bool)
+		for _, name := range s.files[n] {
+			stems[strings.TrimSuffix(name, path.Ext(name))] = true
+		}
+		if len(stems) > 1 {
+			problems = append(problems, fmt.Sprintf("duplicate %02d", n))
+		}
+	}
+	if numbers[0] != 1 {
+		problems = append(problems, fmt.Sprintf("starts at %02d", numbers[0]))
+	}
+	// numbers below the first one are already reported above
+	for i := 1; i < len(numbers); i++ {
+		for missing := numbers[i-1] + 1; missing < numbers[i]; missing++ {
+			problems = append(problems, fmt.Sprintf("missing %02d", missing))
+		}
+	}
+
+	if len(problems) > 0 {
+		quoted := make([]string, 0, len(names))
+		for _, name := range names {
+			quoted = append(quoted, fmt.Sprintf("%q", name))
+		}
+		v.AddError(validator.ValidationError{
+			Actual:         strings.Join(quoted, ", "),
+			Expected:       sequenceExpected,
+			AdditionalInfo: fmt.Sprintf("Folder: %q, date %s: %s", s.folder, s.date, strings.Join(problems, ", ")),
+			Severity:       s.severity,
+			Rule:           sequenceRule,
+			Path:           path.Join(s.folder, s.date+"*"),
+		})
+	}
+
+	return v.Valid()
+}
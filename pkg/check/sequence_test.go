@@ -0,0 +1,110 @@
+package check
+
+import (
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSequenceCheck(t *testing.T) {
+	// allows copies of the same invoice, ie "013119-01 (1).docx"
+	copies := &rules.RuleSet{Rules: []*rules.Rule{
+		{Name: "file-name", AppliesTo: rules.AppliesToFiles, Regex: `^(?P<date>\d{6})-(?P<seq>\d{2})( \(\d+\))?$`, Extensions: []string{".docx", ".pdf"}},
+	}}
+	if err := copies.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ruleSet *rules.RuleSet
+		files   []string
+		want    []string
+	}{
+		{
+			name:  "consecutive",
+			files: []string{"/Invoices/John Doe/013119-02.docx", "/Invoices/John Doe/013119-01.docx", "/Invoices/John Doe/020119-01.docx"},
+		},
+		{
+			name:  "gap",
+			files: []string{"/Invoices/John Doe/013119-01.docx", "/Invoices/John Doe/013119-04.docx"},
+			want:  []string{`/Invoices/John Doe/013119*: Folder: "/Invoices/John Doe", date 013119: missing 02, missing 03`},
+		},
+		{
+			name:    "duplicate",
+			ruleSet: copies,
+			files:   []string{"/Invoices/John Doe/013119-01.docx", "/Invoices/John Doe/013119-01.pdf", "/Invoices/John Doe/013119-02.docx", "/Invoices/John Doe/013119-02 (1).docx"},
+			want:    []string{`/Invoices/John Doe/013119*: Folder: "/Invoices/John Doe", date 013119: duplicate 02`},
+		},
+		{
+			name:  "not starting at 01",
+			files: []string{"/Invoices/John Doe/013119-02.docx", "/Invoices/John Doe/013119-04.docx"},
+			want:  []string{`/Invoices/John Doe/013119*: Folder: "/Invoices/John Doe", date 013119: starts at 02, missing 03`},
+		},
+		{
+			// a file exported to PDF is not a duplicate
+			name:  "same invoice in several formats",
+			files: []string{"/Invoices/John Doe/013119-01.docx", "/Invoices/John Doe/013119-01.pdf"},
+		},
+		{
+			name:  "folders are checked separately",
+			files: []string{"/Invoices/John Doe/013119-01.docx", "/Invoices/Jane Doe/013119-02.docx", "/Invoices/John Doe/2019/013119-03.docx"},
+			want: []string{
+				`/Invoices/Jane Doe/013119*: Folder: "/Invoices/Jane Doe", date 013119: starts at 02`,
+				`/Invoices/John Doe/2019/013119*: Folder: "/Invoices/John Doe/2019", date 013119: starts at 03`,
+			},
+		},
+		{
+			name:  "folders are case insensitive",
+			files: []string{"/Invoices/John Doe/013119-01.docx", "/Invoices/john doe/013119-02.docx"},
+		},
+		{
+			name:  "names without a sequence",
+			files: []string{"/Invoices/John Doe/013119-01.docx", "/Invoices/John Doe/013119-03.docx", "/Invoices/John Doe/1-31-19-02.docx"},
+			want:  []string{`/Invoices/John Doe/013119*: Folder: "/Invoices/John Doe", date 013119: missing 02`},
+		},
+		{
+			name:  "ignored file",
+			files: []string{"/Invoices/John Doe/013119-01.docx", "/Invoices/John Doe/013119-02.docx", "/Invoices/John Doe/013119-05.docx"},
+		},
+	}
+
+	ignored, err := ignore.NewMatcher(nil, []string{"*-05.docx"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleSet := tt.ruleSet
+			if ruleSet == nil {
+				ruleSet = rules.Default()
+			}
+			c := &SequenceCheck{Rules: ruleSet, Ignore: ignored}
+			c.Observe(source.Entry{Name: "John Doe", PathLower: "/invoices/john doe", PathDisplay: "/Invoices/John Doe", IsFolder: true})
+			for _, file := range tt.files {
+				c.Observe(source.Entry{Name: path.Base(file), PathLower: strings.ToLower(file), PathDisplay: file})
+			}
+
+			v := validator.NewValidator()
+			c.Check(v)
+
+			_, errs := v.Valid()
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.Path+": "+e.AdditionalInfo)
+				if e.Rule != sequenceRule {
+					t.Errorf("unexpected rule %q", e.Rule)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected errors:\n%v\ngot:\n%v", tt.want, got)
+			}
+		})
+	}
+}
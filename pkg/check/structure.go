@@ -0,0 +1,112 @@
+package check
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
+)
+
+const (
+	structureRootFilesRule   = "structure-root-files"
+	structureDepthRule       = "structure-depth"
+	structureYearFolderRule  = "structure-year-folder"
+	structureClientFilesRule = "structure-client-files"
+
+	yearFolderRegex = `^\d{4}$`
+)
+
+// StructureRules are the names of the rules of the errors reported by StructureCheck
+var StructureRules = []string{structureRootFilesRule, structureDepthRule, structureYearFolderRule, structureClientFilesRule}
+
+// StructureCheck validates the position of an entry in the folder hierarchy below Root
+type StructureCheck struct {
+	Entry *source.Entry
+	// Root is the display path of the folder being validated, ie "/Invoices"
+	Root      string
+	Structure rules.Structure
+	Ignore    *ignore.Matcher
+}
+
+func (c *StructureCheck) Check(validator *validator.Validator) {
+	if c.Entry.IsFolder && c.Ignore.Folder(c.Entry.PathDisplay) {
+		return
+	}
+	if !c.Entry.IsFolder && c.Ignore.File(c.Entry.PathDisplay) {
+		return
+	}
+
+	root := strings.ToLower(strings.TrimSuffix(c.Root, "/"))
+	if c.Entry.PathLower != root && !strings.HasPrefix(c.Entry.PathLower, root+"/") {
+		log.Debugf("Skipping structure check of %q outside of %q", c.Entry.PathDisplay, c.Root)
+		return
+	}
+
+	// number of path segments below the root, ie 2 for "/Invoices/John Doe/013119-01.docx"
+	depth := len(stringsx.Split(strings.Trim(strings.TrimPrefix(c.Entry.PathLower, root), "/"), "/"))
+	if depth == 0 {
+		return
+	}
+
+	check := structureValidator{
+		entry:     c.Entry,
+		depth:     depth,
+		structure: c.Structure,
+	}
+	validator.Validate(check)
+}
+
+type structureValidator struct {
+	entry     *source.Entry
+	depth     int
+	structure rules.Structure
+}
+
+func (sv structureValidator) Validate() (bool, []validator.ValidationError) {
+	v := validator.NewValidator()
+
+	newError := func(rule string, expected string) validator.ValidationError {
+		kind := "File"
+		if sv.entry.IsFolder {
+			kind = "Folder"
+		}
+		return validator.ValidationError{
+			Actual:         fmt.Sprintf("%q", sv.entry.PathDisplay),
+			Expected:       expected,
+			AdditionalInfo: fmt.Sprintf("%s: %q", kind, sv.entry.PathDisplay),
+			Severity:       rules.SeverityError,
+			Rule:           rule,
+			Path:           sv.entry.PathDisplay,
+		}
+	}
+
+	// the folder containing the invoices, ie "John Doe" or "John Doe/2019"
+	filesDepth := 2
+	if sv.structure.YearFolders {
+		filesDepth = 3
+	}
+
+	switch {
+	case sv.entry.IsFolder && sv.depth > sv.structure.MaxDepth:
+		v.AddError(newError(structureDepthRule,
+			fmt.Sprintf("Folder depth of at most %d below the root folder", sv.structure.MaxDepth)))
+	case sv.entry.IsFolder && sv.structure.YearFolders && sv.depth == 2:
+		if match, _ := regexp.MatchString(yearFolderRegex, sv.entry.Name); !match {
+			v.AddError(newError(structureYearFolderRule,
+				"Client folders only containing year folders, ie \"John Doe/2019\""))
+		}
+	case !sv.entry.IsFolder && sv.depth == 1 && !sv.structure.AllowRootFiles:
+		v.AddError(newError(structureRootFilesRule,
+			"Files inside a client folder, ie \"John Doe/013119-01.docx\""))
+	case !sv.entry.IsFolder && sv.depth > 1 && sv.depth < filesDepth:
+		v.AddError(newError(structureClientFilesRule,
+			"Files inside a year folder, ie \"John Doe/2019/013119-01.docx\""))
+	}
+
+	return v.Valid()
+}
@@ -0,0 +1,77 @@
+package check
+
+import (
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestStructureCheck(t *testing.T) {
+	flat := rules.Structure{MaxDepth: 1}
+	years := rules.Structure{MaxDepth: 2, YearFolders: true}
+	deep := rules.Structure{MaxDepth: 3, AllowRootFiles: true}
+
+	tests := []struct {
+		path      string
+		isFolder  bool
+		structure rules.Structure
+		want      string
+	}{
+		{path: "/Invoices", isFolder: true, structure: flat},
+		{path: "/Invoices/John Doe", isFolder: true, structure: flat},
+		{path: "/Invoices/John Doe/013119-01.docx", structure: flat},
+		{path: "/Invoices/John Doe/2019", isFolder: true, structure: flat, want: structureDepthRule},
+		{path: "/Invoices/013119-01.docx", structure: flat, want: structureRootFilesRule},
+		{path: "/Invoices/013119-01.docx", structure: deep},
+		{path: "/Invoices/John Doe/2019/Paid", isFolder: true, structure: deep},
+		{path: "/Invoices/John Doe/2019/Paid/Old", isFolder: true, structure: deep, want: structureDepthRule},
+
+		{path: "/Invoices/John Doe/2019", isFolder: true, structure: years},
+		{path: "/Invoices/John Doe/2019/013119-01.docx", structure: years},
+		{path: "/Invoices/John Doe/Paid", isFolder: true, structure: years, want: structureYearFolderRule},
+		{path: "/Invoices/John Doe/013119-01.docx", structure: years, want: structureClientFilesRule},
+		{path: "/Invoices/John Doe/2019/Paid", isFolder: true, structure: years, want: structureDepthRule},
+		{path: "/Invoices/013119-01.docx", structure: years, want: structureRootFilesRule},
+
+		// entries outside of the root and ignored entries are not checked
+		{path: "/Archive/John Doe/2019", isFolder: true, structure: flat},
+		{path: "/Invoices-2019/013119-01.docx", structure: flat},
+		{path: "/Invoices/Drafts/2019", isFolder: true, structure: flat},
+		{path: "/Invoices/notes.txt", structure: flat},
+	}
+
+	ignored, err := ignore.NewMatcher([]string{"Drafts"}, []string{"*.txt"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tt := range tests {
+		entry := source.Entry{
+			Name:        path.Base(tt.path),
+			PathLower:   strings.ToLower(tt.path),
+			PathDisplay: tt.path,
+			IsFolder:    tt.isFolder,
+		}
+		c := StructureCheck{
+			Entry:     &entry,
+			Root:      "/Invoices/",
+			Structure: tt.structure,
+			Ignore:    ignored,
+		}
+		v := validator.NewValidator()
+		c.Check(v)
+
+		_, errs := v.Valid()
+		var got string
+		for _, e := range errs {
+			got += e.Rule
+		}
+		if got != tt.want {
+			t.Errorf("%q with %+v: expected %q, got %q", tt.path, tt.structure, tt.want, got)
+		}
+	}
+}
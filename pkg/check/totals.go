@@ -0,0 +1,93 @@
+package check
+
+import (
+	"github.com/dkoshkin/invoices-validator/pkg/document"
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/invoice"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TotalsCheck extracts the line items and totals of the .docx and .pdf invoices for the report,
+// invoices without a line items table or whose line items don't add up to the total are logged as warnings
+type TotalsCheck struct {
+	Rules      *rules.RuleSet
+	Downloader source.Downloader
+	Ignore     *ignore.Matcher
+
+	invoices []*invoice.Invoice
+}
+
+func (c *TotalsCheck) Observe(entry source.Entry) {
+	if entry.IsFolder || !document.Supported(entry.Name) || c.Ignore.File(entry.PathDisplay) {
+		return
+	}
+
+	date, ok := invoiceDate(c.Rules, &entry)
+	if !ok {
+		return
+	}
+
+	content, err := c.Downloader.Download(entry)
+	if err == source.ErrNotDownloadable {
+		log.Warnf("Invoice %q is not in the report: %v", entry.PathDisplay, err)
+		return
+	}
+	if err != nil {
+		log.Errorf("could not extract invoice: %v", err)
+		return
+	}
+	defer content.Close()
+
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		log.Errorf("could not extract invoice: could not read %q: %v", entry.PathDisplay, err)
+		return
+	}
+	doc, err := document.Parse(entry.Name, data)
+	if err != nil {
+		// unreadable documents are reported by the content check
+		log.Debugf("Could not extract invoice %q: %v", entry.PathDisplay, err)
+		return
+	}
+
+	inv, err := invoice.Extract(doc)
+	if err != nil {
+		log.Warnf("Invoice %q is not in the report: %v", entry.PathDisplay, err)
+		return
+	}
+	inv.Path = entry.PathDisplay
+	inv.Client = clientName(&entry)
+	inv.Date = date
+	c.invoices = append(c.invoices, inv)
+
+	if problems := inv.Problems(); len(problems) > 0 {
+		log.Warnf("Line items of invoice %q don't add up to its total: %s", entry.PathDisplay, strings.Join(problems, ", "))
+	}
+}
+
+// Invoices returns all extracted invoices sorted by path,
+// an invoice saved with several extensions, ie a .docx and its exported .pdf, is only returned once
+func (c *TotalsCheck) Invoices() []*invoice.Invoice {
+	sort.Slice(c.invoices, func(i, j int) bool {
+		return c.invoices[i].Path < c.invoices[j].Path
+	})
+
+	invoices := make([]*invoice.Invoice, 0, len(c.invoices))
+	seen := make(map[string]bool)
+	for _, inv := range c.invoices {
+		stem := strings.TrimSuffix(inv.Path, path.Ext(inv.Path))
+		if seen[stem] {
+			continue
+		}
+		seen[stem] = true
+		invoices = append(invoices, inv)
+	}
+
+	return invoices
+}
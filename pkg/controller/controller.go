@@ -2,136 +2,452 @@ package controller
 
 import (
 	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/ack"
 	"github.com/dkoshkin/invoices-validator/pkg/check"
+	"github.com/dkoshkin/invoices-validator/pkg/fixer"
+	"github.com/dkoshkin/invoices-validator/pkg/history"
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/invoice"
 	"github.com/dkoshkin/invoices-validator/pkg/notifier"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/state"
 	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
 	"github.com/dkoshkin/invoices-validator/pkg/validator"
-	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox"
-	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/files"
 	log "github.com/sirupsen/logrus"
-	"github.com/thoas/go-funk"
 	"time"
 
 	"os"
+	"path"
 	"strings"
 )
 
 const (
-	dropboxTokenEnv = "DROPBOX_TOKEN"
-	dropboxPathEnv  = "DROPBOX_PATH"
-
 	foldersToIgnoreEnv = "FOLDERS_TO_IGNORE"
 	filesToIgnoreEnv   = "FILES_TO_IGNORE"
 
+	// invoiceDateCutoffEnv flags invoices dated before it, ie "2019-01-01"
+	invoiceDateCutoffEnv    = "INVOICE_DATE_CUTOFF"
+	invoiceDateCutoffLayout = "2006-01-02"
+
+	// enableContentChecksEnv enables downloading the invoices to validate their content when set to "true"
+	enableContentChecksEnv = "ENABLE_CONTENT_CHECKS"
+
+	// fullScanEnv lists the whole tree even if a cursor from a previous run is stored when set to "true"
+	fullScanEnv = "FULL_SCAN"
+
+	// notifyOnlyOnNewEnv only sends notifications when a run finds errors that were not reported before when set to "true"
+	notifyOnlyOnNewEnv = "NOTIFY_ONLY_ON_NEW"
+
 	notifierSubjectBase = "Failed Invoice Validations"
 )
 
-func Run() error {
-	// validate env vars are set
-	dropboxToken := os.Getenv(dropboxTokenEnv)
-	dropboxPath := os.Getenv(dropboxPathEnv)
+// Options customize a single run, empty values fall back to the environment variables
+type Options struct {
+	// Source is the name of the storage source to list, ie "dropbox" or "local"
+	Source string
+	// Path is the root folder to validate
+	Path string
+	// ContentChecks downloads the invoices to validate their content
+	ContentChecks bool
+	// Report is the format of the invoice totals report, "csv" or "json", no report is written when empty
+	Report string
+	// ReportFile is where to write the report, defaults to stdout
+	ReportFile string
+	// Full lists the whole tree instead of only the changes since the previous run
+	Full bool
+	// StateStore is where to keep state between runs, "file", "bolt" or "dynamodb", overrides $STATE_STORE
+	StateStore string
+	// StateFile is the path of the file and bolt state stores, overrides $STATE_FILE
+	StateFile string
+	// Fix outputs the plan of the proposed renames of the files and folders failing the naming rules instead of notifying about the errors
+	Fix bool
+	// Plan is the format of the plan, "table", "json" or "email", implies Fix, defaults to "table"
+	Plan string
+	// PlanFile is where to write the table or JSON plan, defaults to stdout
+	PlanFile string
+	// Apply renames the files and folders failing the naming rules instead of notifying about the errors
+	Apply bool
+	// ApplyIDs only applies the fixes of a reviewed plan with the IDs
+	ApplyIDs []string
+	// NotifyOnlyOnNew only sends notifications when there are new errors since the previous run
+	NotifyOnlyOnNew bool
+}
 
-	for env, val := range map[string]string{
-		dropboxTokenEnv: dropboxToken,
-		dropboxPathEnv:  dropboxPath} {
-		if val == "" {
-			return fmt.Errorf("%s variable must be set", env)
-		}
+func Run(opts Options) error {
+	src, err := source.ConfiguredSource(opts.Source, opts.Path)
+	if err != nil {
+		return fmt.Errorf("could not configure source: %v", err)
+	}
+
+	ruleSet, err := rules.ConfiguredRules()
+	if err != nil {
+		return fmt.Errorf("could not configure rules: %v", err)
+	}
+	if !ruleSet.Matches(src.Root()) {
+		log.Warnf("No rule applies to the entries under %q, check the paths of the rules", src.Root())
+	}
+
+	store, err := state.ConfiguredStore(opts.StateStore, opts.StateFile)
+	if err != nil {
+		return fmt.Errorf("could not configure state store: %v", err)
+	}
+
+	acks, err := ack.ConfiguredAcks(store)
+	if err != nil {
+		return fmt.Errorf("could not configure acknowledgements: %v", err)
 	}
 
 	notifiers, err := notifier.ConfiguredNotifiers()
 	if err != nil {
 		return fmt.Errorf("could not configure notifiers: %v", err)
 	}
+	for _, n := range notifiers {
+		if setter, ok := n.(notifier.RootSetter); ok {
+			setter.SetRoot(src.Root())
+		}
+	}
 
 	// print some passed in env vars
-	log.Infof("Using path: %q", dropboxPath)
+	log.Infof("Using path: %q", src.Root())
 	foldersToIgnore := stringsx.Split(os.Getenv(foldersToIgnoreEnv), ":")
-	foldersToIgnoreLower := make([]string, 0, len(foldersToIgnore))
-	funk.ForEach(foldersToIgnore, func(x string) {
-		foldersToIgnoreLower = append(foldersToIgnoreLower, strings.ToLower(x))
-	})
 	log.Infof("Ignoring folders: %+v", foldersToIgnore)
 	filesToIgnore := stringsx.Split(os.Getenv(filesToIgnoreEnv), ":")
 	log.Infof("Ignoring file: %+v", filesToIgnore)
+	ignored, err := ignore.NewMatcher(foldersToIgnore, filesToIgnore)
+	if err != nil {
+		return fmt.Errorf("invalid %s or %s: %v", foldersToIgnoreEnv, filesToIgnoreEnv, err)
+	}
 
-	// setup Dropbox client
-	config := dropbox.Config{
-		Token: dropboxToken,
-		//LogLevel: dropbox.LogDebug, // if needed, set the desired logging level. Default is off
+	dates := check.DateOptions{
+		Now: time.Now(),
+	}
+	if cutoff := os.Getenv(invoiceDateCutoffEnv); cutoff != "" {
+		dates.NotBefore, err = time.Parse(invoiceDateCutoffLayout, cutoff)
+		if err != nil {
+			return fmt.Errorf("%s must be a date in the %q format: %v", invoiceDateCutoffEnv, invoiceDateCutoffLayout, err)
+		}
+		log.Infof("Flagging invoices dated before: %s", cutoff)
+	}
+
+	switch opts.Report {
+	case "", invoice.ReportFormatCSV, invoice.ReportFormatJSON:
+	default:
+		return fmt.Errorf("unknown report format %q, must be one of %q or %q", opts.Report, invoice.ReportFormatCSV, invoice.ReportFormatJSON)
 	}
-	dbf := files.New(config)
+
+	switch opts.Plan {
+	case "", fixer.PlanFormatTable, fixer.PlanFormatJSON, fixer.PlanFormatEmail:
+	default:
+		return fmt.Errorf("unknown plan format %q, must be one of %q, %q or %q", opts.Plan, fixer.PlanFormatTable, fixer.PlanFormatJSON, fixer.PlanFormatEmail)
+	}
+
+	contentChecks := opts.ContentChecks || os.Getenv(enableContentChecksEnv) == "true"
+	report := opts.Report
+	var downloader source.Downloader
+	if contentChecks || report != "" {
+		if d, ok := src.(source.Downloader); ok {
+			// the same file is read by the content and totals checks
+			downloader = source.NewLastDownloadCache(d)
+		} else {
+			log.Warn("Source does not support downloading files, skipping content checks and reports")
+			contentChecks, report = false, ""
+		}
+	}
+
+	// a report needs every invoice and fixes check for conflicts with every file so they always list the whole tree
+	fixing := opts.Fix || opts.Plan != "" || opts.Apply || len(opts.ApplyIDs) > 0
+	full := opts.Full || os.Getenv(fullScanEnv) == "true" || report != "" || fixing
+	list, saveCursor, incremental, err := lister(src, store, full)
+	if err != nil {
+		return err
+	}
+
+	var aggregateChecks []check.AggregateChecker
+	if incremental {
+		// gaps and duplicates can only be found when all files of a day are listed
+		log.Info("Only validating changes since the previous run, sequence checks need a full scan with -full")
+	} else {
+		aggregateChecks = append(aggregateChecks, &check.SequenceCheck{
+			Rules:  ruleSet,
+			Ignore: ignored,
+		})
+	}
+
+	// the totals are only extracted for the report, they are not validated
+	var totalsCheck *check.TotalsCheck
+	if report != "" {
+		log.Info("Extracting invoice totals")
+		totalsCheck = &check.TotalsCheck{
+			Rules:      ruleSet,
+			Downloader: downloader,
+			Ignore:     ignored,
+		}
+	}
+
+	// the entries listed by an incremental run, errors about other entries are still outstanding
+	listed := make(map[string]bool)
+	deleted := make(map[string]bool)
 
 	v := validator.NewValidator()
-	hasMore := true
-	cursor := ""
-	for hasMore {
-		// first get the parent folder and a cursor,
-		// then use the cursor to get remaining files and folders
-		res := &files.ListFolderResult{}
-		if cursor == "" {
-			in := &files.ListFolderArg{
-				Path:      dropboxPath,
-				Recursive: true,
+	// the ignore files can be listed after the entries they ignore, so all entries are listed before validating any
+	var entries []source.Entry
+	err = list(func(entry source.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not list entries: %v", err)
+	}
+	if err := loadIgnoreFiles(src, store, incremental, entries, ignored); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		entry := entry
+		listed[entry.PathLower] = true
+		// there is nothing left to validate for removed files/folders
+		if entry.Deleted {
+			deleted[entry.PathLower] = true
+			continue
+		}
+
+		for _, c := range aggregateChecks {
+			c.Observe(entry)
+		}
+		if totalsCheck != nil {
+			totalsCheck.Observe(entry)
+		}
+
+		if ruleSet.Structure != nil {
+			structureCheck := check.StructureCheck{
+				Entry:     &entry,
+				Root:      src.Root(),
+				Structure: *ruleSet.Structure,
+				Ignore:    ignored,
 			}
-			var err error
-			res, err = dbf.ListFolder(in)
-			if err != nil {
-				log.Errorf("could not list folders: %v", err)
+			structureCheck.Check(v)
+		}
+
+		if entry.IsFolder {
+			c := check.FolderCheck{
+				Folder: &entry,
+				Rules:  ruleSet,
+				Ignore: ignored,
 			}
-			cursor = res.Cursor
+			c.Check(v)
 		} else {
-			in := &files.ListFolderContinueArg{
-				Cursor: cursor,
+			c := check.FileCheck{
+				File:   &entry,
+				Rules:  ruleSet,
+				Dates:  dates,
+				Ignore: ignored,
 			}
-			var err error
-			res, err = dbf.ListFolderContinue(in)
-			if err != nil {
-				log.Errorf("could not list folders: %v", err)
-			}
-			cursor = res.Cursor
-		}
-		log.Debugf("Found %d files/folders in the directory", len(res.Entries))
-		hasMore = res.HasMore
-		for _, metadata := range res.Entries {
-			switch metadata.(type) {
-			// Casting types to access the metadata
-			case *files.FolderMetadata:
-				folder, _ := metadata.(*files.FolderMetadata)
-				c := check.FolderCheck{
-					Folder:          folder,
-					FoldersToIgnore: foldersToIgnoreLower,
-				}
-				c.Check(v)
-			case *files.FileMetadata:
-				file, _ := metadata.(*files.FileMetadata)
-				c := check.FileCheck{
-					File:            file,
-					FoldersToIgnore: foldersToIgnoreLower,
-					FilesToIgnore:   filesToIgnore,
+			c.Check(v)
+
+			if contentChecks {
+				contentCheck := check.ContentCheck{
+					File:       &entry,
+					Rules:      ruleSet,
+					Downloader: downloader,
+					Ignore:     ignored,
 				}
-				c.Check(v)
+				contentCheck.Check(v)
 			}
 		}
 	}
 
-	valid, errs := v.Valid()
-	if !valid {
-		log.Infof("Found %d errors", len(errs))
+	// run the checks that need all entries
+	for _, c := range aggregateChecks {
+		c.Check(v)
+	}
+
+	if report != "" {
+		if err := writeReport(report, opts.ReportFile, totalsCheck.Invoices()); err != nil {
+			return fmt.Errorf("could not write report: %v", err)
+		}
+	}
+	if fixing {
+		_, errs := v.Valid()
+		return fix(opts, src, ruleSet, errs, entries, notifiers)
+	}
+
+	// only save the cursor once all entries were validated, otherwise the next run would miss changes
+	if err := saveCursor(); err != nil {
+		return fmt.Errorf("could not save cursor: %v", err)
+	}
+
+	_, errs := v.Valid()
+	log.Infof("Found %d errors", len(errs))
+
+	now := time.Now()
+	var errorHistory *history.History
+	if store != nil {
+		errorHistory, err = history.Load(store, src.Root())
+		if err != nil {
+			return err
+		}
+		ran := rulesRun(ruleSet, incremental, contentChecks)
+		checked := func(e validator.ValidationError) bool {
+			return ran(e.Rule) && (!incremental || listedPath(e.Path, listed, deleted))
+		}
+		errs = errorHistory.Update(errs, checked, now)
+	}
+	acks.Apply(errs, now)
+
+	// acknowledged errors are counted but not notified about
+	newErrs, resolvedErrs, ackedErrs, notifiedErrs := 0, 0, 0, 0
+	for _, e := range errs {
+		switch {
+		case e.Acknowledged:
+			ackedErrs++
+			continue
+		case e.Status == validator.StatusNew:
+			newErrs++
+		case e.Status == validator.StatusResolved:
+			resolvedErrs++
+		}
+		notifiedErrs++
+	}
+	if ackedErrs > 0 {
+		log.Infof("%d errors are acknowledged", ackedErrs)
+	}
+
+	notify := notifiedErrs > 0
+	notifyOnlyOnNew := opts.NotifyOnlyOnNew || os.Getenv(notifyOnlyOnNewEnv) == "true"
+	if errorHistory != nil {
+		log.Infof("%d errors are new and %d were resolved since the previous run", newErrs, resolvedErrs)
+		notify = notify && (newErrs > 0 || !notifyOnlyOnNew)
+	} else if notifyOnlyOnNew {
+		log.Warn("Errors are not tracked between runs without a state store, notifying about all errors")
+	}
 
+	if notify {
+		notifierSubject := fmt.Sprintf("%s - %s", notifierSubjectBase, now.Format("01022006"))
 		for _, n := range notifiers {
-			content, err := n.FormatContent(errs)
-			if err != nil {
-				log.Errorf("could not format content: %v", err)
-			} else {
-				notifierSubject := fmt.Sprintf("%s - %s", notifierSubjectBase, time.Now().Format("01022006"))
-				err = n.Send(notifierSubject, content)
-				if err != nil {
-					log.Errorf("could not send notification: %v", err)
-				}
+			if err := notifier.Dispatch(n, notifierSubject, errs); err != nil {
+				log.Errorf("could not send notification: %v", err)
 			}
 		}
 	}
 
+	if errorHistory != nil {
+		if err := errorHistory.Save(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// Acknowledge saves an acknowledgement of an error in the configured state store
+func Acknowledge(opts Options, a ack.Ack) error {
+	store, err := state.ConfiguredStore(opts.StateStore, opts.StateFile)
+	if err != nil {
+		return fmt.Errorf("could not configure state store: %v", err)
+	}
+	if store == nil {
+		return fmt.Errorf("a state store must be configured to save acknowledgements")
+	}
+
+	if err := ack.Add(store, a, time.Now()); err != nil {
+		return fmt.Errorf("could not acknowledge %q: %v", a.Fingerprint, err)
+	}
+	log.Infof("Acknowledged %q", a.Fingerprint)
+
+	return nil
+}
+
+// rulesRun returns a function reporting whether a rule is validated by this run,
+// the previous errors of the checks that don't run, ie the sequence check of an incremental run, are still outstanding.
+// Rules removed from the rules file are reported as validated so that their previous errors are resolved
+func rulesRun(ruleSet *rules.RuleSet, incremental bool, contentChecks bool) func(rule string) bool {
+	validated := make(map[string]bool)
+	builtin := make(map[string]bool)
+	for _, names := range [][]string{check.SequenceRules, check.StructureRules, check.ContentRules} {
+		for _, name := range names {
+			builtin[name] = true
+		}
+	}
+
+	names := check.NameRules(ruleSet)
+	if !incremental {
+		names = append(names, check.SequenceRules...)
+	}
+	if ruleSet.Structure != nil {
+		names = append(names, check.StructureRules...)
+	}
+	if contentChecks {
+		names = append(names, check.ContentRules...)
+	}
+	for _, name := range names {
+		validated[name] = true
+	}
+
+	return func(rule string) bool {
+		return validated[rule] || !builtin[rule]
+	}
+}
+
+// listedPath returns true if the entry at the path was listed, or any of its parent folders was deleted
+func listedPath(p string, listed map[string]bool, deleted map[string]bool) bool {
+	p = strings.ToLower(p)
+	if listed[p] {
+		return true
+	}
+	for dir := path.Dir(p); dir != "/" && dir != "."; dir = path.Dir(dir) {
+		if deleted[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// lister returns a function to list the source entries and a function to save the position of the listing for the next run,
+// only the changes since the previous run are listed when the source supports it, a cursor was stored and full is false
+func lister(src source.Source, store state.Store, full bool) (func(fn func(entry source.Entry) error) error, func() error, bool, error) {
+	incrementalSrc, ok := src.(source.IncrementalSource)
+	if store == nil || !ok {
+		return src.List, func() error { return nil }, false, nil
+	}
+
+	key := "cursor:" + src.Root()
+	var cursor string
+	if !full {
+		value, err := store.Get(key)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("could not read cursor: %v", err)
+		}
+		cursor = string(value)
+	}
+
+	var next string
+	list := func(fn func(entry source.Entry) error) error {
+		var err error
+		next, err = incrementalSrc.ListChanges(cursor, fn)
+		return err
+	}
+	save := func() error {
+		return store.Put(key, []byte(next))
+	}
+
+	return list, save, cursor != "", nil
+}
+
+func writeReport(format string, file string, invoices []*invoice.Invoice) error {
+	if file == "" {
+		return invoice.WriteReport(os.Stdout, format, invoices)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := invoice.WriteReport(f, format, invoices); err != nil {
+		f.Close()
+		return err
+	}
+	log.Infof("Report with %d invoices written to: %q", len(invoices), file)
+
+	return f.Close()
+}
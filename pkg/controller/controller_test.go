@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/dkoshkin/invoices-validator/pkg/history"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/state"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeFixture creates the files under dir, folders are created as needed
+func writeFixture(t *testing.T, dir string, files ...string) {
+	for _, file := range files {
+		p := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// savedErrors returns the rule and path of the errors saved by the last run of the root
+func savedErrors(t *testing.T, stateFile string, root string) []string {
+	store, err := state.NewFileStore(stateFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := store.Get("errors:" + root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var errs []validator.ValidationError
+	if err := json.Unmarshal(data, &errs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saved := make([]string, 0, len(errs))
+	for _, e := range errs {
+		saved = append(saved, e.Rule+" "+e.Path)
+	}
+	return saved
+}
+
+func TestRunLocalSource(t *testing.T) {
+	for _, env := range []string{"SOURCE", "RULES_FILE", "STATE_STORE", "ENABLED_NOTIFIERS", foldersToIgnoreEnv, filesToIgnoreEnv,
+		invoiceDateCutoffEnv, enableContentChecksEnv, fullScanEnv, notifyOnlyOnNewEnv} {
+		t.Setenv(env, "")
+	}
+
+	dir := filepath.Join(t.TempDir(), "Invoices")
+	writeFixture(t, dir,
+		"John Doe/013119-01.docx",
+		"John Doe/013119-03.docx",
+		"John Doe/1-31-19.docx",
+		"Doe, Jane/020119-01.pdf",
+	)
+	opts := Options{
+		Source:     "local",
+		Path:       dir,
+		StateStore: "file",
+		StateFile:  filepath.Join(t.TempDir(), "state.json"),
+		Full:       true,
+	}
+
+	if err := Run(opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"file-name /Invoices/John Doe/1-31-19.docx",
+		"file-sequence /Invoices/John Doe/013119*",
+		"folder-name /Invoices/Doe, Jane",
+	}
+	if got := savedErrors(t, opts.StateFile, "/Invoices"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected errors:\n%v\ngot:\n%v", want, got)
+	}
+
+	// fixing the files resolves their errors on the next run
+	if err := os.Rename(filepath.Join(dir, "John Doe", "1-31-19.docx"), filepath.Join(dir, "John Doe", "013119-02.docx")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []string{"folder-name /Invoices/Doe, Jane"}
+	if got := savedErrors(t, opts.StateFile, "/Invoices"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected errors:\n%v\ngot:\n%v", want, got)
+	}
+}
+
+func TestRulesRun(t *testing.T) {
+	ruleSet := rules.Default()
+	withStructure := rules.Default()
+	withStructure.Structure = &rules.Structure{MaxDepth: 1}
+
+	tests := []struct {
+		name          string
+		ruleSet       *rules.RuleSet
+		incremental   bool
+		contentChecks bool
+		ran           []string
+		notRan        []string
+	}{
+		{
+			name:    "full run",
+			ruleSet: ruleSet,
+			ran:     []string{"file-name", "folder-name", "file-date", "file-sequence"},
+			notRan:  []string{"content-date", "content-client", "content-unreadable", "structure-depth"},
+		},
+		{
+			name:        "incremental run",
+			ruleSet:     ruleSet,
+			incremental: true,
+			ran:         []string{"file-name", "folder-name", "file-date"},
+			notRan:      []string{"file-sequence"},
+		},
+		{
+			name:          "content checks and structure",
+			ruleSet:       withStructure,
+			contentChecks: true,
+			ran:           []string{"content-date", "content-client", "content-unreadable", "structure-depth", "structure-root-files"},
+		},
+		{
+			// the errors of a rule removed from the rules file are resolved
+			name:    "removed rule",
+			ruleSet: ruleSet,
+			ran:     []string{"hourly-file-name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := rulesRun(tt.ruleSet, tt.incremental, tt.contentChecks)
+			for _, rule := range tt.ran {
+				if !ran(rule) {
+					t.Errorf("expected %q to run", rule)
+				}
+			}
+			for _, rule := range tt.notRan {
+				if ran(rule) {
+					t.Errorf("expected %q not to run", rule)
+				}
+			}
+		})
+	}
+}
+
+func TestListedPath(t *testing.T) {
+	listed := map[string]bool{
+		"/invoices/john doe/013119-01.docx": true,
+		"/invoices/jane doe":                true,
+	}
+	deleted := map[string]bool{
+		"/invoices/jane doe": true,
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/Invoices/John Doe/013119-01.docx", want: true},
+		{path: "/Invoices/John Doe/013119-02.docx", want: false},
+		{path: "/Invoices/John Doe", want: false},
+		// the entries of deleted folders are not listed again
+		{path: "/Invoices/Jane Doe", want: true},
+		{path: "/Invoices/Jane Doe/2019/013119-01.docx", want: true},
+		{path: "/Invoices/Jane Doe*", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := listedPath(tt.path, listed, deleted); got != tt.want {
+			t.Errorf("listedPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// fakeIncrementalSource returns the next cursor and records the cursors it lists the changes from
+type fakeIncrementalSource struct {
+	next    string
+	cursors []string
+}
+
+func (s *fakeIncrementalSource) Root() string {
+	return "/Invoices"
+}
+
+func (s *fakeIncrementalSource) List(fn func(entry source.Entry) error) error {
+	_, err := s.ListChanges("", fn)
+	return err
+}
+
+func (s *fakeIncrementalSource) ListChanges(cursor string, fn func(entry source.Entry) error) (string, error) {
+	s.cursors = append(s.cursors, cursor)
+	return s.next, nil
+}
+
+func TestLister(t *testing.T) {
+	store, err := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	src := &fakeIncrementalSource{}
+	noop := func(entry source.Entry) error { return nil }
+
+	runs := []struct {
+		name            string
+		full            bool
+		save            bool
+		next            string
+		wantCursor      string
+		wantIncremental bool
+	}{
+		{name: "first run", save: true, next: "1"},
+		{name: "incremental run", save: true, next: "2", wantCursor: "1", wantIncremental: true},
+		// the cursor is only saved once the entries were validated
+		{name: "failed run", next: "3", wantCursor: "2", wantIncremental: true},
+		{name: "retried run", save: true, next: "3", wantCursor: "2", wantIncremental: true},
+		{name: "full run", full: true, save: true, next: "4"},
+		{name: "run after a full run", next: "5", wantCursor: "4", wantIncremental: true},
+	}
+
+	for _, run := range runs {
+		src.next = run.next
+		list, save, incremental, err := lister(src, store, run.full)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", run.name, err)
+		}
+		if incremental != run.wantIncremental {
+			t.Errorf("%s: expected incremental %v, got %v", run.name, run.wantIncremental, incremental)
+		}
+		if err := list(noop); err != nil {
+			t.Fatalf("%s: unexpected error: %v", run.name, err)
+		}
+		if cursor := src.cursors[len(src.cursors)-1]; cursor != run.wantCursor {
+			t.Errorf("%s: expected to list from cursor %q, got %q", run.name, run.wantCursor, cursor)
+		}
+		if run.save {
+			if err := save(); err != nil {
+				t.Fatalf("%s: unexpected error: %v", run.name, err)
+			}
+		}
+	}
+
+	// without a state store every run lists the whole tree
+	list, save, incremental, err := lister(src, nil, false)
+	if err != nil || incremental {
+		t.Fatalf("expected a full listing, got incremental %v, %v", incremental, err)
+	}
+	if err := list(noop); err != nil || save() != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cursor := src.cursors[len(src.cursors)-1]; cursor != "" {
+		t.Errorf("expected to list from scratch, got cursor %q", cursor)
+	}
+}
+
+func TestIncrementalRunHistory(t *testing.T) {
+	store, err := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := time.Date(2019, 1, 31, 10, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	previous := []validator.ValidationError{
+		{Rule: "file-name", Path: "/Invoices/John Doe/1-31-19.docx"},
+		{Rule: "file-name", Path: "/Invoices/John Doe/2-1-19.docx"},
+		{Rule: "folder-name", Path: "/Invoices/Doe, Jane"},
+		{Rule: "file-name", Path: "/Invoices/Doe, Jane/2-1-19.docx"},
+		{Rule: "file-sequence", Path: "/Invoices/John Doe/013119*"},
+	}
+	h, err := history.Load(store, "/Invoices")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h.Update(previous, func(e validator.ValidationError) bool { return true }, first)
+	if err := h.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the next incremental run lists a renamed file, a still invalid file and a deleted folder
+	listed := map[string]bool{
+		"/invoices/john doe/1-31-19.docx":   true,
+		"/invoices/john doe/013119-01.docx": true,
+		"/invoices/john doe/2-1-19.docx":    true,
+		"/invoices/doe, jane":               true,
+	}
+	deleted := map[string]bool{
+		"/invoices/john doe/1-31-19.docx": true,
+		"/invoices/doe, jane":             true,
+	}
+	ran := rulesRun(rules.Default(), true, false)
+	checked := func(e validator.ValidationError) bool {
+		return ran(e.Rule) && listedPath(e.Path, listed, deleted)
+	}
+
+	h, err = history.Load(store, "/Invoices")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errs := h.Update([]validator.ValidationError{previous[1]}, checked, second)
+
+	got := make(map[string]string)
+	for _, e := range errs {
+		got[e.Rule+" "+e.Path] = e.Status
+		if e.Status != validator.StatusResolved && !e.Since.Equal(first) {
+			t.Errorf("expected %q to be reported since %v, got %v", e.Path, first, e.Since)
+		}
+	}
+	want := map[string]string{
+		"file-name /Invoices/John Doe/1-31-19.docx": validator.StatusResolved,
+		"file-name /Invoices/John Doe/2-1-19.docx":  validator.StatusOutstanding,
+		"folder-name /Invoices/Doe, Jane":           validator.StatusResolved,
+		"file-name /Invoices/Doe, Jane/2-1-19.docx": validator.StatusResolved,
+		"file-sequence /Invoices/John Doe/013119*":  validator.StatusOutstanding,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected statuses:\n%v\ngot:\n%v", want, got)
+	}
+}
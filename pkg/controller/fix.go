@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/fixer"
+	"github.com/dkoshkin/invoices-validator/pkg/notifier"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"time"
+)
+
+const planSubjectBase = "Proposed Invoice Renames"
+
+// fix proposes renames for the errors, then either outputs the plan for review or applies it
+func fix(opts Options, src source.Source, ruleSet *rules.RuleSet, errs []validator.ValidationError, entries []source.Entry, notifiers []notifier.Notifier) error {
+	byPath := make(map[string]source.Entry, len(entries))
+	for _, entry := range entries {
+		byPath[entry.PathLower] = entry
+	}
+
+	f := fixer.Fixer{Rules: ruleSet}
+	fixes := f.Propose(errs, byPath)
+	log.Infof("Found %d errors, %d can be fixed by renaming", len(errs), len(fixes))
+
+	if !opts.Apply && len(opts.ApplyIDs) == 0 {
+		return writePlan(opts, fixes, notifiers)
+	}
+
+	if len(opts.ApplyIDs) > 0 {
+		selected, err := fixer.Select(fixes, opts.ApplyIDs)
+		if err != nil {
+			return err
+		}
+		fixes = selected
+	}
+
+	renamer, ok := src.(source.Renamer)
+	if !ok {
+		return fmt.Errorf("source does not support renaming files")
+	}
+	applied, err := fixer.Apply(renamer, fixes)
+	log.Infof("Renamed %d files/folders", len(applied))
+
+	return err
+}
+
+func writePlan(opts Options, fixes []fixer.Fix, notifiers []notifier.Notifier) error {
+	format := opts.Plan
+	if format == "" {
+		format = fixer.PlanFormatTable
+	}
+
+	if format == fixer.PlanFormatEmail {
+		sent := false
+		for _, n := range notifiers {
+			formatter, ok := n.(notifier.PlanFormatter)
+			if !ok {
+				continue
+			}
+			// routed contacts only get the renames under their folders
+			subject := fmt.Sprintf("%s - %s", planSubjectBase, time.Now().Format("01022006"))
+			if err := notifier.DispatchPlan(n, formatter, subject, fixes); err != nil {
+				return fmt.Errorf("could not send plan: %v", err)
+			}
+			sent = true
+		}
+		if !sent {
+			return fmt.Errorf("the email notifier must be enabled to send the plan")
+		}
+		return nil
+	}
+
+	if opts.PlanFile == "" {
+		return fixer.WritePlan(os.Stdout, format, fixes)
+	}
+
+	f, err := os.Create(opts.PlanFile)
+	if err != nil {
+		return err
+	}
+	if err := fixer.WritePlan(f, format, fixes); err != nil {
+		f.Close()
+		return err
+	}
+	log.Infof("Plan with %d renames written to: %q", len(fixes), opts.PlanFile)
+
+	return f.Close()
+}
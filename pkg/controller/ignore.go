@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/ignore"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/state"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// loadIgnoreFiles downloads the ignore files of the listed entries and adds their patterns to the matcher.
+// Incremental runs only list the ignore files that changed so the content of all ignore files is kept in the store
+func loadIgnoreFiles(src source.Source, store state.Store, incremental bool, entries []source.Entry, ignored *ignore.Matcher) error {
+	key := "ignorefiles:" + src.Root()
+	// the content of the ignore files keyed by their display path
+	files := make(map[string]string)
+	if incremental {
+		data, err := store.Get(key)
+		if err != nil {
+			return fmt.Errorf("could not read ignore files: %v", err)
+		}
+		if data != nil {
+			if err := json.Unmarshal(data, &files); err != nil {
+				return fmt.Errorf("could not decode ignore files: %v", err)
+			}
+		}
+	}
+
+	changed := false
+	for _, entry := range entries {
+		if entry.Deleted {
+			// a deleted folder also deletes the ignore files inside it
+			for p := range files {
+				if strings.EqualFold(p, entry.PathDisplay) || strings.HasPrefix(strings.ToLower(p), entry.PathLower+"/") {
+					delete(files, p)
+					changed = true
+				}
+			}
+			continue
+		}
+		if entry.IsFolder || entry.Name != ignore.FileName {
+			continue
+		}
+
+		downloader, ok := src.(source.Downloader)
+		if !ok {
+			log.Warnf("Source does not support downloading files, ignoring %q", entry.PathDisplay)
+			continue
+		}
+		content, err := downloader.Download(entry)
+		if err == source.ErrNotDownloadable {
+			log.Warnf("Ignoring %q: %v", entry.PathDisplay, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not download %q: %v", entry.PathDisplay, err)
+		}
+		data, err := ioutil.ReadAll(content)
+		content.Close()
+		if err != nil {
+			return fmt.Errorf("could not download %q: %v", entry.PathDisplay, err)
+		}
+		files[entry.PathDisplay] = string(data)
+		changed = true
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if err := ignored.AddFile(path.Dir(p), []byte(files[p])); err != nil {
+			return fmt.Errorf("invalid ignore file %q: %v", p, err)
+		}
+		log.Infof("Using ignore file: %q", p)
+	}
+
+	// full runs list every ignore file, always save them so that deleted ignore files are not applied by later runs
+	if store != nil && (changed || !incremental) {
+		data, err := json.Marshal(files)
+		if err != nil {
+			return fmt.Errorf("could not encode ignore files: %v", err)
+		}
+		if err := store.Put(key, data); err != nil {
+			return fmt.Errorf("could not save ignore files: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package document
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	docxExtension = ".docx"
+	pdfExtension  = ".pdf"
+)
+
+// Document is the text content of an invoice file
+type Document struct {
+	Paragraphs []string
+	// Tables are lists of rows of cell texts
+	Tables []Table
+}
+
+type Table [][]string
+
+// Text returns all paragraphs separated by new lines
+func (d *Document) Text() string {
+	return strings.Join(d.Paragraphs, "\n")
+}
+
+// Supported reports whether the content of the file can be parsed based on its extension
+func Supported(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case docxExtension, pdfExtension:
+		return true
+	}
+	return false
+}
+
+// Parse extracts the text of a .docx or .pdf file
+func Parse(name string, data []byte) (*Document, error) {
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case docxExtension:
+		return parseDocx(data)
+	case pdfExtension:
+		return parsePDF(data)
+	default:
+		return nil, fmt.Errorf("unsupported document type %q", ext)
+	}
+}
@@ -0,0 +1,152 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// wordNamespace is the namespace of the WordprocessingML elements
+	wordNamespace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+	documentPart = "word/document.xml"
+)
+
+// parseDocx extracts the text of an Office Open XML word processing document,
+// the paragraphs of the body are followed by the ones in the headers and footers
+func parseDocx(data []byte) (*Document, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("could not open document archive: %v", err)
+	}
+
+	parts := make(map[string]*zip.File)
+	var extraParts []string
+	for _, f := range archive.File {
+		parts[f.Name] = f
+		dir, name := path.Split(f.Name)
+		if dir == "word/" && path.Ext(name) == ".xml" && (strings.HasPrefix(name, "header") || strings.HasPrefix(name, "footer")) {
+			extraParts = append(extraParts, f.Name)
+		}
+	}
+	sort.Strings(extraParts)
+
+	if _, ok := parts[documentPart]; !ok {
+		return nil, fmt.Errorf("%q not found, not a word processing document", documentPart)
+	}
+
+	doc := &Document{}
+	for i, name := range append([]string{documentPart}, extraParts...) {
+		part, err := readPart(parts[name])
+		if err != nil {
+			return nil, fmt.Errorf("could not read %q: %v", name, err)
+		}
+		doc.Paragraphs = append(doc.Paragraphs, part.Paragraphs...)
+		// tables in headers and footers are only used for the layout
+		if i == 0 {
+			doc.Tables = part.Tables
+		}
+	}
+
+	return doc, nil
+}
+
+func readPart(f *zip.File) (*Document, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return readBody(xml.NewDecoder(r))
+}
+
+// readBody returns the text of every non empty <w:p> element and the text of the cells of every top level <w:tbl>
+func readBody(decoder *xml.Decoder) (*Document, error) {
+	doc := &Document{}
+	var current strings.Builder
+	inText := false
+
+	// nested tables are flattened into the cells of the top level table
+	tableDepth := 0
+	var table Table
+	var row []string
+	var cell []string
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return doc, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space != wordNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					table = Table{}
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = []string{}
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell = []string{}
+				}
+			case "t":
+				inText = true
+			case "tab":
+				current.WriteString("\t")
+			case "br", "cr":
+				current.WriteString("\n")
+			}
+		case xml.EndElement:
+			if t.Name.Space != wordNamespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if text := strings.TrimSpace(current.String()); text != "" {
+					doc.Paragraphs = append(doc.Paragraphs, text)
+					if tableDepth > 0 {
+						cell = append(cell, text)
+					}
+				}
+				current.Reset()
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if tableDepth == 1 {
+					table = append(table, row)
+				}
+			case "tbl":
+				if tableDepth == 1 {
+					doc.Tables = append(doc.Tables, table)
+				}
+				tableDepth--
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ledongthuc/pdf"
+	"strings"
+)
+
+const (
+	// the page tree of an invoice is shallow and small, the limits stop malformed or cyclic trees from hanging the run
+	maxPDFTreeDepth = 32
+	maxPDFTreeNodes = 10000
+)
+
+// parsePDF extracts the text of a PDF file line by line.
+// PDFs have no notion of tables, every line is added as a row of a single table
+// with each separately positioned piece of text as a cell, which works well for tables exported from Word
+func parsePDF(data []byte) (doc *Document, err error) {
+	// the PDF library panics on many malformed files instead of returning errors
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("could not read malformed PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("could not open PDF: %v", err)
+	}
+
+	pages, err := pdfPages(reader.Trailer().Key("Root").Key("Pages"))
+	if err != nil {
+		return nil, err
+	}
+
+	doc = &Document{}
+	table := Table{}
+	for i, page := range pages {
+		rows, err := page.GetTextByRow()
+		if err != nil {
+			return nil, fmt.Errorf("could not read text of page %d: %v", i+1, err)
+		}
+		for _, row := range rows {
+			var cells []string
+			for _, text := range row.Content {
+				if s := strings.TrimSpace(text.S); s != "" {
+					cells = append(cells, s)
+				}
+			}
+			if len(cells) == 0 {
+				continue
+			}
+			doc.Paragraphs = append(doc.Paragraphs, strings.Join(cells, " "))
+			table = append(table, cells)
+		}
+	}
+	if len(table) > 0 {
+		doc.Tables = []Table{table}
+	}
+
+	return doc, nil
+}
+
+// pdfPages walks the page tree in order, unlike pdf.Reader.Page it fails on an invalid /Kids
+// instead of looping forever and bounds the walk of cyclic trees
+func pdfPages(root pdf.Value) ([]pdf.Page, error) {
+	var pages []pdf.Page
+	nodes := 0
+	var walk func(node pdf.Value, depth int) error
+	walk = func(node pdf.Value, depth int) error {
+		nodes++
+		if depth > maxPDFTreeDepth || nodes > maxPDFTreeNodes {
+			return fmt.Errorf("invalid PDF page tree: too deep or too large")
+		}
+
+		switch node.Key("Type").Name() {
+		case "Page":
+			pages = append(pages, pdf.Page{V: node})
+		case "Pages":
+			kids := node.Key("Kids")
+			if kids.Kind() != pdf.Array {
+				return fmt.Errorf("invalid PDF page tree: /Kids is not an array")
+			}
+			for i := 0; i < kids.Len(); i++ {
+				if err := walk(kids.Index(i), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(root, 0); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
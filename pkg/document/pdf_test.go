@@ -0,0 +1,97 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildPDF returns a PDF with the numbered objects and a valid xref table, object 1 is the catalog
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestParsePDF(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		pages   int
+		wantErr string
+	}{
+		{
+			name: "empty page",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+				"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>",
+			),
+		},
+		{
+			name: "malformed page object",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+				"<< /Type /Page /Parent 2 0 R ) ] >>",
+			),
+			wantErr: "malformed PDF",
+		},
+		{
+			name: "kids is not an array",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids 7 /Count 1 >>",
+			),
+			wantErr: "/Kids is not an array",
+		},
+		{
+			name: "cyclic page tree",
+			data: buildPDF(
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [2 0 R] /Count 1 >>",
+			),
+			wantErr: "too deep or too large",
+		},
+		{
+			name:    "not a PDF",
+			data:    []byte("not a PDF"),
+			wantErr: "could not open PDF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() {
+				_, err := parsePDF(tt.data)
+				done <- err
+			}()
+
+			select {
+			case err := <-done:
+				if tt.wantErr == "" && err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("parsing did not finish")
+			}
+		})
+	}
+}
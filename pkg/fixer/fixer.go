@@ -0,0 +1,178 @@
+package fixer
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// ConfidenceHigh fixes only change separators, padding or case
+	ConfidenceHigh = "high"
+	// ConfidenceMedium fixes reorder or reformat parts of the name
+	ConfidenceMedium = "medium"
+	// ConfidenceLow fixes drop parts of the name
+	ConfidenceLow = "low"
+)
+
+var confidenceRank = map[string]int{
+	ConfidenceLow:    0,
+	ConfidenceMedium: 1,
+	ConfidenceHigh:   2,
+}
+
+// Fix is a proposed rename of a file or folder that failed a naming rule
+type Fix struct {
+	// ID identifies the fix across runs as long as the names don't change, so that a reviewed plan can be applied later
+	ID    string       `json:"id"`
+	Entry source.Entry `json:"-"`
+	// From and To are the display paths before and after the rename
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Rule       string `json:"rule"`
+	Confidence string `json:"confidence"`
+	// Conflict explains why the fix can't be applied, ie the new name is already used
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// Fixer proposes canonical names for the entries that failed the naming rules
+type Fixer struct {
+	Rules *rules.RuleSet
+}
+
+// Propose returns the fixes for the errors, entries are all the listed entries keyed by their lowercase path.
+// Errors that are not about a naming rule or have no likely canonical name are skipped
+func (f *Fixer) Propose(errs []validator.ValidationError, entries map[string]source.Entry) []Fix {
+	var fixes []Fix
+	proposed := make(map[string]bool)
+	for _, e := range errs {
+		if e.Status == validator.StatusResolved || proposed[strings.ToLower(e.Path)] {
+			continue
+		}
+		entry, ok := entries[strings.ToLower(e.Path)]
+		if !ok || entry.Deleted || !f.namingRule(entry, e.Rule) {
+			continue
+		}
+
+		fix, ok := f.propose(entry)
+		if !ok {
+			log.Debugf("No fix found for %q", entry.PathDisplay)
+			continue
+		}
+		fix.Rule = e.Rule
+		proposed[strings.ToLower(e.Path)] = true
+		fixes = append(fixes, fix)
+	}
+
+	detectConflicts(fixes, entries)
+	return fixes
+}
+
+// namingRule returns true if the rule is one of the naming rules of the entry
+func (f *Fixer) namingRule(entry source.Entry, rule string) bool {
+	for _, r := range f.Rules.For(entry) {
+		if r.Name == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// propose returns the first candidate name that passes all the naming rules of the entry
+func (f *Fixer) propose(entry source.Entry) (Fix, bool) {
+	candidates := fileCandidates(entry.Name)
+	if entry.IsFolder {
+		candidates = folderCandidates(entry.Name)
+	}
+
+	for _, c := range candidates {
+		if c.name == entry.Name || !f.valid(entry, c.name) {
+			continue
+		}
+		to := path.Join(path.Dir(entry.PathDisplay), c.name)
+		return Fix{
+			ID:         fixID(entry.PathDisplay, to),
+			Entry:      entry,
+			From:       entry.PathDisplay,
+			To:         to,
+			Confidence: c.confidence,
+		}, true
+	}
+
+	return Fix{}, false
+}
+
+// fixID is a short hash of the rename
+func fixID(from string, to string) string {
+	sum := sha1.Sum([]byte(from + "\x00" + to))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+func (f *Fixer) valid(entry source.Entry, name string) bool {
+	for _, r := range f.Rules.For(entry) {
+		if !r.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// detectConflicts sets the Conflict of fixes renaming to an existing entry or to the same name as another fix,
+// names are compared case-insensitively as Dropbox paths are case-insensitive
+func detectConflicts(fixes []Fix, entries map[string]source.Entry) {
+	targets := make(map[string]int)
+	for _, fix := range fixes {
+		targets[strings.ToLower(fix.To)]++
+	}
+
+	for i := range fixes {
+		to := strings.ToLower(fixes[i].To)
+		if existing, ok := entries[to]; ok && !existing.Deleted && to != strings.ToLower(fixes[i].From) {
+			fixes[i].Conflict = fmt.Sprintf("%q already exists", existing.PathDisplay)
+		} else if targets[to] > 1 {
+			fixes[i].Conflict = fmt.Sprintf("%d files would be renamed to %q", targets[to], fixes[i].To)
+		}
+	}
+}
+
+// Apply renames the entries of the fixes without conflicts, files are renamed before the folders containing them
+// and a failed rename doesn't stop the others. Returns the fixes that were applied
+func Apply(renamer source.Renamer, fixes []Fix) ([]Fix, error) {
+	ordered := make([]Fix, len(fixes))
+	copy(ordered, fixes)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Entry.IsFolder != ordered[j].Entry.IsFolder {
+			return !ordered[i].Entry.IsFolder
+		}
+		// deepest folders first
+		return strings.Count(ordered[i].From, "/") > strings.Count(ordered[j].From, "/")
+	})
+
+	var applied []Fix
+	var failed []string
+	for _, fix := range ordered {
+		if fix.Conflict != "" {
+			log.Warnf("Not renaming %q: %s", fix.From, fix.Conflict)
+			continue
+		}
+		if err := renamer.Rename(fix.Entry, path.Base(fix.To)); err != nil {
+			log.Errorf("could not rename %q to %q: %v", fix.From, fix.To, err)
+			failed = append(failed, fix.From)
+			continue
+		}
+		log.Infof("Renamed %q to %q", fix.From, fix.To)
+		applied = append(applied, fix)
+	}
+
+	if len(failed) > 0 {
+		return applied, fmt.Errorf("could not rename %d files/folders", len(failed))
+	}
+	return applied, nil
+}
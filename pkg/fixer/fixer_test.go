@@ -0,0 +1,107 @@
+package fixer
+
+import (
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newEntry(p string, isFolder bool) source.Entry {
+	return source.Entry{Name: path.Base(p), PathLower: strings.ToLower(p), PathDisplay: p, IsFolder: isFolder}
+}
+
+func TestPropose(t *testing.T) {
+	tests := []struct {
+		from           string
+		isFolder       bool
+		rule           string
+		wantTo         string
+		wantConfidence string
+		wantID         string
+	}{
+		{from: "/Invoices/John Doe/013119_01.docx", rule: "file-name", wantTo: "/Invoices/John Doe/013119-01.docx", wantConfidence: ConfidenceHigh, wantID: "54c95f6e"},
+		{from: "/Invoices/John Doe/013119-1.docx", rule: "file-name", wantTo: "/Invoices/John Doe/013119-01.docx", wantConfidence: ConfidenceHigh, wantID: "6091d80e"},
+		{from: "/Invoices/John Doe/013119-01.DOCX", rule: "file-name", wantTo: "/Invoices/John Doe/013119-01.docx", wantConfidence: ConfidenceHigh, wantID: "10163fc8"},
+		{from: "/Invoices/John Doe/1-31-19-1.docx", rule: "file-name", wantTo: "/Invoices/John Doe/013119-01.docx", wantConfidence: ConfidenceMedium, wantID: "231c5196"},
+		{from: "/Invoices/John Doe/01312019-01.pdf", rule: "file-name", wantTo: "/Invoices/John Doe/013119-01.pdf", wantConfidence: ConfidenceMedium, wantID: "40a2b535"},
+		{from: "/Invoices/John Doe/John Doe 013119-02.docx", rule: "file-name", wantTo: "/Invoices/John Doe/013119-02.docx", wantConfidence: ConfidenceLow, wantID: "88739772"},
+		{from: "/Invoices/Doe, John", isFolder: true, rule: "folder-name", wantTo: "/Invoices/John Doe", wantConfidence: ConfidenceMedium, wantID: "1ab610f6"},
+		{from: "/Invoices/Jane  Smith", isFolder: true, rule: "folder-name", wantTo: "/Invoices/Jane Smith", wantConfidence: ConfidenceHigh, wantID: "1f32f415"},
+		// no likely canonical name
+		{from: "/Invoices/John Doe/invoice.docx", rule: "file-name"},
+		{from: "/Invoices/John Doe/013119-01 013119-02.docx", rule: "file-name"},
+		{from: "/Invoices/John Doe/13-31-19-1.docx", rule: "file-name"},
+		{from: "/Invoices/Doe, John, Jr", isFolder: true, rule: "folder-name"},
+		// only the errors of naming rules are fixed
+		{from: "/Invoices/John Doe/013119_01.docx", rule: "file-sequence"},
+	}
+
+	f := &Fixer{Rules: rules.Default()}
+	for _, tt := range tests {
+		entry := newEntry(tt.from, tt.isFolder)
+		errs := []validator.ValidationError{{Rule: tt.rule, Path: tt.from}}
+		fixes := f.Propose(errs, map[string]source.Entry{entry.PathLower: entry})
+
+		if tt.wantTo == "" {
+			if len(fixes) != 0 {
+				t.Errorf("%q: expected no fix, got %+v", tt.from, fixes)
+			}
+			continue
+		}
+		if len(fixes) != 1 {
+			t.Errorf("%q: expected a fix, got %+v", tt.from, fixes)
+			continue
+		}
+		fix := fixes[0]
+		if fix.From != tt.from || fix.To != tt.wantTo || fix.Confidence != tt.wantConfidence || fix.Rule != tt.rule || fix.Conflict != "" {
+			t.Errorf("%q: expected a %s confidence fix to %q, got %+v", tt.from, tt.wantConfidence, tt.wantTo, fix)
+		}
+		// the ID of a reviewed plan must not change between runs
+		if fix.ID != tt.wantID {
+			t.Errorf("%q: expected ID %q, got %q", tt.from, tt.wantID, fix.ID)
+		}
+	}
+}
+
+func TestProposeConflicts(t *testing.T) {
+	entries := make(map[string]source.Entry)
+	for _, p := range []string{
+		"/Invoices/John Doe/013119-01.docx",
+		"/Invoices/John Doe/013119_01.docx",
+		"/Invoices/John Doe/020119_01.docx",
+		"/Invoices/John Doe/020119-1.docx",
+		"/Invoices/John Doe/020219_01.docx",
+	} {
+		entries[strings.ToLower(p)] = newEntry(p, false)
+	}
+	var errs []validator.ValidationError
+	for _, p := range []string{
+		"/Invoices/John Doe/013119_01.docx",
+		"/Invoices/John Doe/020119_01.docx",
+		"/Invoices/John Doe/020119-1.docx",
+		"/Invoices/John Doe/020219_01.docx",
+		// reported twice, ie by the name and the date rules
+		"/Invoices/John Doe/020219_01.docx",
+	} {
+		errs = append(errs, validator.ValidationError{Rule: "file-name", Path: p})
+	}
+
+	f := &Fixer{Rules: rules.Default()}
+	conflicts := make(map[string]string)
+	for _, fix := range f.Propose(errs, entries) {
+		conflicts[fix.From] = fix.Conflict
+	}
+	want := map[string]string{
+		"/Invoices/John Doe/013119_01.docx": `"/Invoices/John Doe/013119-01.docx" already exists`,
+		"/Invoices/John Doe/020119_01.docx": `2 files would be renamed to "/Invoices/John Doe/020119-01.docx"`,
+		"/Invoices/John Doe/020119-1.docx":  `2 files would be renamed to "/Invoices/John Doe/020119-01.docx"`,
+		"/Invoices/John Doe/020219_01.docx": "",
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("expected conflicts:\n%v\ngot:\n%v", want, conflicts)
+	}
+}
@@ -0,0 +1,190 @@
+package fixer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// heuristic transforms a name that failed a rule to what was likely meant,
+// returns false if it doesn't apply to the name
+type heuristic struct {
+	confidence string
+	transform  func(name string) (string, bool)
+}
+
+var (
+	// "013119_01" or "013119 01"
+	separatorRegex = regexp.MustCompile(`^(\d{6})[ _.]+(\d{1,2})$`)
+	// "013119-1"
+	unpaddedSequenceRegex = regexp.MustCompile(`^(\d{6})-(\d)$`)
+	// "1-31-19-1", "01.31.2019_01" or "1 31 19 1"
+	separatedDateRegex = regexp.MustCompile(`^(\d{1,2})[-_. ](\d{1,2})[-_. ](\d{4}|\d{2})[-_. ]+(\d{1,2})$`)
+	// "01312019-01"
+	fullYearRegex = regexp.MustCompile(`^(\d{2})(\d{2})(\d{2})(\d{2})[-_. ]+(\d{1,2})$`)
+	// "John Doe 013119-01" or "013119-01 copy"
+	embeddedRegex = regexp.MustCompile(`(?:^|\D)(\d{6})[-_. ]?(\d{2})(?:\D|$)`)
+	// "Doe, John"
+	lastFirstRegex  = regexp.MustCompile(`^([^,]+),\s*(.+)$`)
+	whitespaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// fileHeuristics are tried in order on the name of a file without its extension
+var fileHeuristics = []heuristic{
+	{
+		confidence: ConfidenceHigh,
+		transform: func(stem string) (string, bool) {
+			m := separatorRegex.FindStringSubmatch(stem)
+			if m == nil {
+				return "", false
+			}
+			return fmt.Sprintf("%s-%s", m[1], pad(m[2])), true
+		},
+	},
+	{
+		confidence: ConfidenceHigh,
+		transform: func(stem string) (string, bool) {
+			m := unpaddedSequenceRegex.FindStringSubmatch(stem)
+			if m == nil {
+				return "", false
+			}
+			return fmt.Sprintf("%s-0%s", m[1], m[2]), true
+		},
+	},
+	{
+		confidence: ConfidenceMedium,
+		transform: func(stem string) (string, bool) {
+			m := separatedDateRegex.FindStringSubmatch(stem)
+			if m == nil {
+				return "", false
+			}
+			return fmt.Sprintf("%s%s%s-%s", pad(m[1]), pad(m[2]), shortYear(m[3]), pad(m[4])), true
+		},
+	},
+	{
+		confidence: ConfidenceMedium,
+		transform: func(stem string) (string, bool) {
+			m := fullYearRegex.FindStringSubmatch(stem)
+			if m == nil || (m[3] != "19" && m[3] != "20") {
+				return "", false
+			}
+			return fmt.Sprintf("%s%s%s-%s", m[1], m[2], m[4], pad(m[5])), true
+		},
+	},
+	{
+		// dropping the rest of the name could lose information
+		confidence: ConfidenceLow,
+		transform: func(stem string) (string, bool) {
+			matches := embeddedMatches(stem)
+			if len(matches) != 1 {
+				return "", false
+			}
+			return fmt.Sprintf("%s-%s", matches[0][0], matches[0][1]), true
+		},
+	},
+}
+
+// folderHeuristics are applied one after the other on the name of a folder
+var folderHeuristics = []heuristic{
+	{
+		confidence: ConfidenceHigh,
+		transform: func(name string) (string, bool) {
+			return whitespaceRegex.ReplaceAllString(strings.TrimSpace(name), " "), true
+		},
+	},
+	{
+		confidence: ConfidenceMedium,
+		transform: func(name string) (string, bool) {
+			m := lastFirstRegex.FindStringSubmatch(name)
+			if m == nil || strings.Contains(m[2], ",") {
+				return "", false
+			}
+			return fmt.Sprintf("%s %s", strings.TrimSpace(m[2]), strings.TrimSpace(m[1])), true
+		},
+	},
+	{
+		confidence: ConfidenceMedium,
+		transform: func(name string) (string, bool) {
+			// mixed case like "John McDonald" is likely intended
+			if name != strings.ToLower(name) && name != strings.ToUpper(name) {
+				return "", false
+			}
+			words := strings.Split(name, " ")
+			for i, word := range words {
+				if word != "" {
+					words[i] = strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+				}
+			}
+			return strings.Join(words, " "), true
+		},
+	},
+}
+
+// fileCandidates returns the possible canonical names of a file, with their confidence
+func fileCandidates(name string) []candidate {
+	ext := path.Ext(name)
+	stem := strings.TrimSpace(strings.TrimSuffix(name, ext))
+	ext = strings.ToLower(ext)
+
+	// only the extension is wrong, ie "013119-01.DOCX"
+	candidates := []candidate{{name: stem + ext, confidence: ConfidenceHigh}}
+	for _, h := range fileHeuristics {
+		if fixed, ok := h.transform(stem); ok {
+			candidates = append(candidates, candidate{name: fixed + ext, confidence: h.confidence})
+		}
+	}
+	return candidates
+}
+
+// folderCandidates returns the possible canonical names of a folder, with their confidence,
+// every heuristic is applied on the result of the previous ones and the lowest confidence so far is kept
+func folderCandidates(name string) []candidate {
+	var candidates []candidate
+	confidence := ConfidenceHigh
+	for _, h := range folderHeuristics {
+		fixed, ok := h.transform(name)
+		if !ok || fixed == name {
+			continue
+		}
+		name = fixed
+		if confidenceRank[h.confidence] < confidenceRank[confidence] {
+			confidence = h.confidence
+		}
+		candidates = append(candidates, candidate{name: name, confidence: confidence})
+	}
+	return candidates
+}
+
+// embeddedMatches returns the date and sequence of every invoice name in the stem,
+// the separator following a match can start the next one, ie "013119-01 013119-02"
+func embeddedMatches(stem string) [][2]string {
+	var matches [][2]string
+	for start := 0; start < len(stem); {
+		loc := embeddedRegex.FindStringSubmatchIndex(stem[start:])
+		if loc == nil {
+			break
+		}
+		matches = append(matches, [2]string{stem[start+loc[2] : start+loc[3]], stem[start+loc[4] : start+loc[5]]})
+		start += loc[5]
+	}
+	return matches
+}
+
+type candidate struct {
+	name       string
+	confidence string
+}
+
+func pad(number string) string {
+	n, _ := strconv.Atoi(number)
+	return fmt.Sprintf("%02d", n)
+}
+
+func shortYear(year string) string {
+	if len(year) == 4 {
+		return year[2:]
+	}
+	return year
+}
@@ -0,0 +1,63 @@
+package fixer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	PlanFormatTable = "table"
+	PlanFormatJSON  = "json"
+	// PlanFormatEmail sends the plan for review with the email notifier
+	PlanFormatEmail = "email"
+)
+
+// WritePlan writes the proposed fixes as a table or JSON
+func WritePlan(w io.Writer, format string, fixes []Fix) error {
+	switch format {
+	case PlanFormatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tFROM\tTO\tRULE\tCONFIDENCE\tCONFLICT")
+		for _, fix := range fixes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", fix.ID, fix.From, fix.To, fix.Rule, fix.Confidence, fix.Conflict)
+		}
+		return tw.Flush()
+	case PlanFormatJSON:
+		if fixes == nil {
+			fixes = []Fix{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(fixes)
+	}
+
+	return fmt.Errorf("unknown plan format %q", format)
+}
+
+// Select returns the fixes with the IDs, it fails if an ID is not one of the fixes,
+// ie because the file was renamed or fixed since the plan was reviewed
+func Select(fixes []Fix, ids []string) ([]Fix, error) {
+	byID := make(map[string]Fix, len(fixes))
+	for _, fix := range fixes {
+		byID[fix.ID] = fix
+	}
+
+	var selected []Fix
+	var unknown []string
+	for _, id := range ids {
+		fix, ok := byID[strings.ToLower(strings.TrimSpace(id))]
+		if !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		selected = append(selected, fix)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("fixes %v are not proposed anymore, review the plan again", unknown)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,85 @@
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash separated name matches the shell pattern.
+// In addition to the path.Match syntax a "**" segment matches zero or more path segments,
+// ie "/Invoices/**/*.docx" matches both "/Invoices/a.docx" and "/Invoices/John Doe/2019/a.docx"
+func Match(pattern, name string) (bool, error) {
+	return matchSegments(split(pattern), split(name))
+}
+
+// MatchBelow reports whether the pattern can match dir or a path below it,
+// ie "/Invoices/*/2019/**" can match paths below "/Invoices" but not below "/Archive"
+func MatchBelow(pattern, dir string) (bool, error) {
+	segments, names := split(pattern), split(dir)
+	for len(names) > 0 {
+		if len(segments) == 0 {
+			return false, nil
+		}
+		if segments[0] == "**" {
+			return true, nil
+		}
+		ok, err := path.Match(segments[0], names[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		segments = segments[1:]
+		names = names[1:]
+	}
+
+	return true, nil
+}
+
+// Validate returns an error if the pattern is malformed
+func Validate(pattern string) error {
+	for _, segment := range split(pattern) {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func split(s string) []string {
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "/")
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// collapse consecutive "**" and try to match the rest at every possible depth
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchSegments(pattern, name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0, nil
+}
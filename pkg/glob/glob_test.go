@@ -0,0 +1,32 @@
+package glob
+
+import "testing"
+
+func TestMatchBelow(t *testing.T) {
+	tests := []struct {
+		pattern string
+		dir     string
+		want    bool
+	}{
+		{pattern: "**", dir: "/Invoices", want: true},
+		{pattern: "/Invoices/**", dir: "/Invoices", want: true},
+		{pattern: "/Invoices/**", dir: "/Invoices/John Doe", want: true},
+		{pattern: "/Invoices/*/2019/**", dir: "/Invoices", want: true},
+		{pattern: "/Invoices/*/2019/**", dir: "/Invoices/John Doe/2019", want: true},
+		{pattern: "/Invoices/*/2019/**", dir: "/Invoices/John Doe/2018", want: false},
+		{pattern: "/Invoices/Hourly", dir: "/", want: true},
+		{pattern: "/Invoices/Hourly", dir: "/Invoices/Hourly/2019", want: false},
+		{pattern: "/Invoices/**", dir: "/Archive", want: false},
+		{pattern: "/Invoices/**", dir: "/Invoices-2019", want: false},
+	}
+
+	for _, tt := range tests {
+		got, err := MatchBelow(tt.pattern, tt.dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("MatchBelow(%q, %q) = %v, want %v", tt.pattern, tt.dir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/state"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"sort"
+	"time"
+)
+
+// History is the set of errors reported by the previous run
+type History struct {
+	store state.Store
+	key   string
+
+	// errors keyed by their fingerprint
+	errors map[string]validator.ValidationError
+}
+
+// Load reads the errors reported by the previous run of the root folder
+func Load(store state.Store, root string) (*History, error) {
+	h := &History{
+		store:  store,
+		key:    "errors:" + root,
+		errors: make(map[string]validator.ValidationError),
+	}
+
+	data, err := store.Get(h.key)
+	if err != nil {
+		return nil, fmt.Errorf("could not read previous errors: %v", err)
+	}
+	if data == nil {
+		return h, nil
+	}
+
+	var errs []validator.ValidationError
+	if err := json.Unmarshal(data, &errs); err != nil {
+		return nil, fmt.Errorf("could not decode previous errors: %v", err)
+	}
+	for _, e := range errs {
+		h.errors[e.Fingerprint()] = e
+	}
+
+	return h, nil
+}
+
+// Update sets the Status and Since of the errors found by this run and returns them
+// followed by the errors of the previous run that are now resolved.
+// Previous errors that checked returns false for, ie files that were not listed by an incremental run,
+// are still outstanding and are returned with the errors of this run
+func (h *History) Update(errs []validator.ValidationError, checked func(e validator.ValidationError) bool, now time.Time) []validator.ValidationError {
+	current := make(map[string]validator.ValidationError, len(errs))
+	updated := make([]validator.ValidationError, 0, len(errs))
+	for _, e := range errs {
+		fingerprint := e.Fingerprint()
+		e.Status = validator.StatusNew
+		e.Since = now
+		if previous, ok := h.errors[fingerprint]; ok {
+			e.Status = validator.StatusOutstanding
+			e.Since = previous.Since
+		}
+		// the same rule can fail more than once for a file, ie a date both invalid and before the cutoff
+		if _, ok := current[fingerprint]; !ok {
+			current[fingerprint] = e
+		}
+		updated = append(updated, e)
+	}
+
+	var unchecked, resolved []validator.ValidationError
+	for fingerprint, previous := range h.errors {
+		if _, ok := current[fingerprint]; ok {
+			continue
+		}
+		if checked(previous) {
+			previous.Status = validator.StatusResolved
+			resolved = append(resolved, previous)
+		} else {
+			previous.Status = validator.StatusOutstanding
+			current[fingerprint] = previous
+			unchecked = append(unchecked, previous)
+		}
+	}
+	sortErrors(unchecked)
+	sortErrors(resolved)
+
+	h.errors = current
+	updated = append(updated, unchecked...)
+	return append(updated, resolved...)
+}
+
+// Save stores the errors of the last Update for the next run
+func (h *History) Save() error {
+	errs := make([]validator.ValidationError, 0, len(h.errors))
+	for _, e := range h.errors {
+		errs = append(errs, e)
+	}
+	sortErrors(errs)
+
+	data, err := json.Marshal(errs)
+	if err != nil {
+		return fmt.Errorf("could not encode errors: %v", err)
+	}
+	if err := h.store.Put(h.key, data); err != nil {
+		return fmt.Errorf("could not save errors: %v", err)
+	}
+
+	return nil
+}
+
+func sortErrors(errs []validator.ValidationError) {
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Fingerprint() < errs[j].Fingerprint()
+	})
+}
@@ -0,0 +1,110 @@
+package ignore
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/glob"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexPrefix marks a pattern as a regular expression matched against the full path, ie "regex=\.tmp$"
+const regexPrefix = "regex="
+
+// Patterns is a list of gitignore-style patterns:
+//  - a pattern without a "/", ie "*.xlsx" or "~$*", matches the name of an entry in any folder
+//  - a pattern with a "/", ie "/Invoices/Archive/**", matches the full path of an entry, "**" matches any number of folders
+//  - a pattern starting with "!" re-includes the entries matched by the previous patterns
+//  - a pattern starting with "regex=" is a regular expression matched against the full path
+// The last pattern matching an entry decides if it is ignored
+type Patterns struct {
+	patterns        []pattern
+	caseInsensitive bool
+}
+
+type pattern struct {
+	raw    string
+	glob   string
+	regex  *regexp.Regexp
+	negate bool
+	// anchored patterns are matched against the full path instead of the name
+	anchored bool
+}
+
+// Compile parses the patterns, base is the folder anchored patterns are relative to, ie "/" or "/Invoices/John Doe"
+func Compile(patterns []string, base string, caseInsensitive bool) (*Patterns, error) {
+	ps := &Patterns{caseInsensitive: caseInsensitive}
+	for _, raw := range patterns {
+		p := pattern{raw: raw}
+		s := raw
+		if strings.HasPrefix(s, "!") {
+			p.negate = true
+			s = s[1:]
+		}
+
+		if strings.HasPrefix(s, regexPrefix) {
+			expr := strings.TrimPrefix(s, regexPrefix)
+			if caseInsensitive {
+				expr = "(?i)" + expr
+			}
+			regex, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %v", raw, err)
+			}
+			p.regex = regex
+			ps.patterns = append(ps.patterns, p)
+			continue
+		}
+
+		// a trailing "/" only marks folders in gitignore, files and folders are configured separately here
+		s = strings.TrimSuffix(s, "/")
+		if s == "" {
+			return nil, fmt.Errorf("invalid pattern %q: must not be empty", raw)
+		}
+		if strings.Contains(s, "/") {
+			p.anchored = true
+			s = path.Join(base, s)
+		}
+		if caseInsensitive {
+			s = strings.ToLower(s)
+		}
+		if err := glob.Validate(s); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", raw, err)
+		}
+		p.glob = s
+		ps.patterns = append(ps.patterns, p)
+	}
+
+	return ps, nil
+}
+
+// Match returns true if the entry at the slash separated full path is ignored
+func (ps *Patterns) Match(fullPath string) bool {
+	if ps == nil {
+		return false
+	}
+
+	if ps.caseInsensitive {
+		fullPath = strings.ToLower(fullPath)
+	}
+	ignored := false
+	for _, p := range ps.patterns {
+		if p.match(fullPath) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+func (p pattern) match(fullPath string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(fullPath)
+	}
+	if !p.anchored {
+		// already validated
+		ok, _ := path.Match(p.glob, path.Base(fullPath))
+		return ok
+	}
+	ok, _ := glob.Match(p.glob, fullPath)
+	return ok
+}
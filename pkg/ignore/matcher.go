@@ -0,0 +1,112 @@
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strings"
+)
+
+// FileName is the name of the files listing patterns to ignore in the folder containing them
+const FileName = ".invoiceignore"
+
+// Matcher decides if folders and files are ignored,
+// everything inside an ignored folder is ignored
+type Matcher struct {
+	Folders *Patterns
+	Files   *Patterns
+
+	scopes []scope
+}
+
+// scope are the patterns of an ignore file, they only apply below the folder containing it
+type scope struct {
+	// folder is the lowercase full path of the folder containing the ignore file
+	folder   string
+	patterns *Patterns
+}
+
+// NewMatcher compiles the folder and file patterns anchored at "/",
+// folder patterns are case-insensitive and file patterns are case-sensitive
+func NewMatcher(folders []string, files []string) (*Matcher, error) {
+	folderPatterns, err := Compile(folders, "/", true)
+	if err != nil {
+		return nil, err
+	}
+	filePatterns, err := Compile(files, "/", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Matcher{
+		Folders: folderPatterns,
+		Files:   filePatterns,
+	}, nil
+}
+
+// AddFile adds the patterns of an ignore file in the folder, they apply to both files and folders below it,
+// are case-insensitive and anchored patterns are relative to the folder
+func (m *Matcher) AddFile(folder string, data []byte) error {
+	patterns, err := Compile(ParseFile(data), folder, true)
+	if err != nil {
+		return err
+	}
+
+	m.scopes = append(m.scopes, scope{
+		folder:   strings.ToLower(path.Clean(folder)),
+		patterns: patterns,
+	})
+	return nil
+}
+
+// ParseFile returns the patterns of an ignore file, one per line, skipping empty lines and "#" comments
+func ParseFile(data []byte) []string {
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// Folder returns true if the folder at the full path or any of its parents is ignored
+func (m *Matcher) Folder(fullPath string) bool {
+	if m == nil {
+		return false
+	}
+
+	for p := path.Clean(fullPath); p != "/" && p != "."; p = path.Dir(p) {
+		if m.Folders.Match(p) || m.scoped(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// File returns true if the file at the full path or any of its parent folders is ignored,
+// ignore files are always ignored
+func (m *Matcher) File(fullPath string) bool {
+	if path.Base(fullPath) == FileName {
+		return true
+	}
+	if m == nil {
+		return false
+	}
+
+	return m.Files.Match(fullPath) || m.scoped(fullPath) || m.Folder(path.Dir(fullPath))
+}
+
+// scoped returns true if the patterns of an ignore file in a parent folder match the full path
+func (m *Matcher) scoped(fullPath string) bool {
+	lower := strings.ToLower(fullPath)
+	for _, s := range m.scopes {
+		if strings.HasPrefix(lower, strings.TrimSuffix(s.folder, "/")+"/") && s.patterns.Match(fullPath) {
+			return true
+		}
+	}
+	return false
+}
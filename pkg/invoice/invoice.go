@@ -0,0 +1,233 @@
+package invoice
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/document"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tolerance when comparing amounts, in dollars
+const tolerance = 0.005
+
+var (
+	// header keywords of the line items table columns
+	dateHeaders        = []string{"date"}
+	descriptionHeaders = []string{"description", "service", "item"}
+	quantityHeaders    = []string{"sessions", "session", "hours", "hrs", "qty", "quantity", "units"}
+	rateHeaders        = []string{"rate", "price", "fee"}
+	amountHeaders      = []string{"amount", "total", "charge", "cost"}
+
+	totalRegex  = regexp.MustCompile(`(?i)\btotal(\s+due)?\s*:?\s*\$?\s*(\(?[\d,]+(\.\d+)?\)?)`)
+	amountRegex = regexp.MustCompile(`^\(?-?[\d,]*\.?\d+\)?$`)
+)
+
+type LineItem struct {
+	Date        string  `json:"date,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Quantity    float64 `json:"quantity"`
+	Rate        float64 `json:"rate"`
+	Amount      float64 `json:"amount"`
+}
+
+// Invoice is the structured content of an invoice file
+type Invoice struct {
+	Path   string     `json:"path"`
+	Client string     `json:"client"`
+	Date   time.Time  `json:"date"`
+	Items  []LineItem `json:"items"`
+	// Total is the total stated in the invoice, zero if none was found
+	Total float64 `json:"total"`
+}
+
+// Extract parses the line items and the total of an invoice document,
+// the line items are read from the first table with a recognizable amount column
+func Extract(doc *document.Document) (*Invoice, error) {
+	inv := &Invoice{}
+
+	foundTable := false
+	for _, table := range doc.Tables {
+		items, total, ok := extractTable(table)
+		if !ok {
+			continue
+		}
+		foundTable = true
+		inv.Items = items
+		inv.Total = total
+		break
+	}
+	if !foundTable {
+		return nil, fmt.Errorf("no line items table found")
+	}
+
+	// the total is commonly written below the table
+	if inv.Total == 0 {
+		for _, paragraph := range doc.Paragraphs {
+			if match := totalRegex.FindStringSubmatch(paragraph); match != nil {
+				if total, ok := parseAmount(match[2]); ok {
+					inv.Total = total
+				}
+			}
+		}
+	}
+
+	return inv, nil
+}
+
+// Sum returns the sum of all line item amounts
+func (inv *Invoice) Sum() float64 {
+	sum := 0.0
+	for _, item := range inv.Items {
+		sum += item.Amount
+	}
+	return sum
+}
+
+// Amount returns the stated total, falling back to the sum of the line items when no total was found
+func (inv *Invoice) Amount() float64 {
+	if inv.Total == 0 {
+		return inv.Sum()
+	}
+	return inv.Total
+}
+
+// Problems returns the inconsistencies between the line items and the total
+func (inv *Invoice) Problems() []string {
+	var problems []string
+	for i, item := range inv.Items {
+		if item.Quantity != 0 && item.Rate != 0 && !equal(item.Quantity*item.Rate, item.Amount) {
+			problems = append(problems, fmt.Sprintf("line item %d: %s x %s is %s, not %s",
+				i+1, formatNumber(item.Quantity), FormatAmount(item.Rate), FormatAmount(item.Quantity*item.Rate), FormatAmount(item.Amount)))
+		}
+	}
+
+	if inv.Total == 0 {
+		problems = append(problems, "no total found")
+	} else if !equal(inv.Sum(), inv.Total) {
+		problems = append(problems, fmt.Sprintf("line items sum to %s, not the stated total %s",
+			FormatAmount(inv.Sum()), FormatAmount(inv.Total)))
+	}
+
+	return problems
+}
+
+// extractTable returns the line items and the total of a table with an amount column,
+// rows before the header row, ie titles, are skipped
+func extractTable(table document.Table) ([]LineItem, float64, bool) {
+	headerRow := -1
+	var dateColumn, descriptionColumn, quantityColumn, rateColumn, amountColumn int
+	for i, row := range table {
+		dateColumn = column(row, dateHeaders)
+		descriptionColumn = column(row, descriptionHeaders)
+		quantityColumn = column(row, quantityHeaders)
+		rateColumn = column(row, rateHeaders)
+		amountColumn = column(row, amountHeaders)
+		// an amount column alone is likely a "Total: $100" line, not a header
+		if amountColumn >= 0 && (dateColumn >= 0 || descriptionColumn >= 0 || quantityColumn >= 0 || rateColumn >= 0) {
+			headerRow = i
+			break
+		}
+	}
+	if headerRow < 0 {
+		return nil, 0, false
+	}
+
+	var items []LineItem
+	total := 0.0
+	for _, row := range table[headerRow+1:] {
+		if isTotalRow(row) {
+			// the total is the last amount in the row, ie "Total | | | $400.00"
+			for i := len(row) - 1; i >= 0; i-- {
+				if amount, ok := parseAmount(row[i]); ok {
+					total = amount
+					break
+				}
+			}
+			continue
+		}
+
+		amount, ok := parseAmount(cellAt(row, amountColumn))
+		if !ok {
+			continue
+		}
+		item := LineItem{
+			Date:        cellAt(row, dateColumn),
+			Description: cellAt(row, descriptionColumn),
+			Amount:      amount,
+		}
+		item.Quantity, _ = parseAmount(cellAt(row, quantityColumn))
+		item.Rate, _ = parseAmount(cellAt(row, rateColumn))
+		items = append(items, item)
+	}
+
+	return items, total, true
+}
+
+// column returns the index of the first header cell containing one of the keywords, or -1
+func column(header []string, keywords []string) int {
+	for _, keyword := range keywords {
+		for i, cell := range header {
+			if strings.Contains(strings.ToLower(cell), keyword) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func cellAt(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+func isTotalRow(row []string) bool {
+	for _, cell := range row {
+		cell = strings.ToLower(strings.TrimSpace(cell))
+		if cell == "" {
+			continue
+		}
+		return strings.HasPrefix(cell, "total")
+	}
+	return false
+}
+
+// parseAmount parses numbers like "$1,200.50" or "(25.00)"
+func parseAmount(s string) (float64, bool) {
+	s = strings.Replace(strings.TrimSpace(s), "$", "", -1)
+	s = strings.Replace(s, " ", "", -1)
+	if s == "" || !amountRegex.MatchString(s) {
+		return 0, false
+	}
+
+	negative := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	s = strings.Trim(s, "()")
+	s = strings.Replace(s, ",", "", -1)
+
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return amount, true
+}
+
+func equal(a, b float64) bool {
+	return math.Abs(a-b) < tolerance
+}
+
+// FormatAmount formats an amount in dollars, ie "$1200.50"
+func FormatAmount(amount float64) string {
+	return fmt.Sprintf("$%.2f", amount)
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
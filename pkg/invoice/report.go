@@ -0,0 +1,99 @@
+package invoice
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+const (
+	ReportFormatCSV  = "csv"
+	ReportFormatJSON = "json"
+
+	monthLayout = "2006-01"
+)
+
+// Summary is the billed amount of a client in a month
+type Summary struct {
+	Client   string  `json:"client"`
+	Month    string  `json:"month"`
+	Invoices int     `json:"invoices"`
+	Quantity float64 `json:"quantity"`
+	Total    float64 `json:"total"`
+}
+
+// Summarize aggregates the invoices per client and month, sorted by client then month
+func Summarize(invoices []*Invoice) []Summary {
+	type key struct {
+		client string
+		month  string
+	}
+
+	summaries := make(map[key]*Summary)
+	for _, inv := range invoices {
+		k := key{client: inv.Client, month: inv.Date.Format(monthLayout)}
+		s, ok := summaries[k]
+		if !ok {
+			s = &Summary{Client: k.client, Month: k.month}
+			summaries[k] = s
+		}
+		s.Invoices++
+		for _, item := range inv.Items {
+			s.Quantity += item.Quantity
+		}
+		s.Total += inv.Amount()
+	}
+
+	result := make([]Summary, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Client != result[j].Client {
+			return result[i].Client < result[j].Client
+		}
+		return result[i].Month < result[j].Month
+	})
+
+	return result
+}
+
+// WriteReport writes the monthly summaries as CSV, or the summaries and all invoices as JSON
+func WriteReport(w io.Writer, format string, invoices []*Invoice) error {
+	summaries := Summarize(invoices)
+
+	switch format {
+	case ReportFormatCSV:
+		writer := csv.NewWriter(w)
+		rows := [][]string{{"client", "month", "invoices", "quantity", "total"}}
+		for _, s := range summaries {
+			rows = append(rows, []string{
+				s.Client,
+				s.Month,
+				strconv.Itoa(s.Invoices),
+				formatNumber(s.Quantity),
+				fmt.Sprintf("%.2f", s.Total),
+			})
+		}
+		return writer.WriteAll(rows)
+	case ReportFormatJSON:
+		if invoices == nil {
+			invoices = []*Invoice{}
+		}
+		report := struct {
+			Summaries []Summary  `json:"summaries"`
+			Invoices  []*Invoice `json:"invoices"`
+		}{
+			Summaries: summaries,
+			Invoices:  invoices,
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	return fmt.Errorf("unknown report format %q, must be one of %q or %q", format, ReportFormatCSV, ReportFormatJSON)
+}
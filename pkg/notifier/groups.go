@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"time"
+)
+
+// errorGroups splits the errors by their status for the notifications
+type errorGroups struct {
+	// Tracked is false when errors are not tracked between runs and all errors are in New
+	Tracked     bool
+	New         []groupedError
+	Outstanding []groupedError
+	Resolved    []groupedError
+	// Acknowledged is the number of acknowledged errors that are not listed
+	Acknowledged int
+}
+
+type groupedError struct {
+	validator.ValidationError
+	// Days is how many days ago the error was first reported
+	Days int
+}
+
+// errorSection is a titled list of errors of the same status
+type errorSection struct {
+	Title  string
+	Errors []groupedError
+}
+
+// Summary returns the additional info of the error marked with its severity and, for outstanding errors, its age
+func (e groupedError) Summary() string {
+	summary := e.AdditionalInfo
+	if e.Severity == rules.SeverityWarning {
+		summary = "[warning] " + summary
+	}
+	if e.Status == validator.StatusOutstanding {
+		summary = fmt.Sprintf("%s (%d days)", summary, e.Days)
+	}
+	return summary
+}
+
+// Sections returns the non empty groups in the order they are notified about
+func (g errorGroups) Sections() []errorSection {
+	if !g.Tracked {
+		return []errorSection{{Title: "Below is the list of failed validators:", Errors: g.New}}
+	}
+
+	var sections []errorSection
+	for _, section := range []errorSection{
+		{Title: "New failed validators:", Errors: g.New},
+		{Title: "Still outstanding:", Errors: g.Outstanding},
+		{Title: "Resolved since the last run:", Errors: g.Resolved},
+	} {
+		if len(section.Errors) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// AcknowledgedNote returns the note about the acknowledged errors that are not listed, empty if there are none
+func (g errorGroups) AcknowledgedNote() string {
+	if g.Acknowledged == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d acknowledged errors are not listed.", g.Acknowledged)
+}
+
+func groupErrors(errs []validator.ValidationError, now time.Time) errorGroups {
+	var groups errorGroups
+	for _, e := range errs {
+		if e.Acknowledged {
+			if e.Status != validator.StatusResolved {
+				groups.Acknowledged++
+			}
+			continue
+		}
+
+		g := groupedError{ValidationError: e}
+		if !e.Since.IsZero() {
+			g.Days = int(now.Sub(e.Since).Hours() / 24)
+		}
+
+		switch e.Status {
+		case validator.StatusOutstanding:
+			groups.Tracked = true
+			groups.Outstanding = append(groups.Outstanding, g)
+		case validator.StatusResolved:
+			groups.Tracked = true
+			groups.Resolved = append(groups.Resolved, g)
+		case validator.StatusNew:
+			groups.Tracked = true
+			groups.New = append(groups.New, g)
+		default:
+			groups.New = append(groups.New, g)
+		}
+	}
+
+	return groups
+}
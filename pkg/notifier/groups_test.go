@@ -0,0 +1,87 @@
+package notifier
+
+import (
+	"github.com/dkoshkin/invoices-validator/pkg/rules"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorGroupsSections(t *testing.T) {
+	now := time.Date(2019, 2, 1, 10, 0, 0, 0, time.UTC)
+	tracked := []validator.ValidationError{
+		{Rule: "file-name", Path: "/Invoices/a.docx", AdditionalInfo: "File: a", Status: validator.StatusNew, Since: now},
+		{Rule: "file-name", Path: "/Invoices/b.docx", AdditionalInfo: "File: b", Status: validator.StatusOutstanding, Since: now.Add(-72 * time.Hour), Severity: rules.SeverityWarning},
+		{Rule: "file-name", Path: "/Invoices/c.docx", AdditionalInfo: "File: c", Status: validator.StatusResolved},
+		{Rule: "file-name", Path: "/Invoices/d.docx", AdditionalInfo: "File: d", Status: validator.StatusOutstanding, Acknowledged: true},
+	}
+
+	tests := []struct {
+		name         string
+		errs         []validator.ValidationError
+		wantSections map[string][]string
+		wantTitles   []string
+		wantAckNote  string
+	}{
+		{
+			name:         "untracked",
+			errs:         []validator.ValidationError{{AdditionalInfo: "File: a", Severity: rules.SeverityWarning}},
+			wantTitles:   []string{"Below is the list of failed validators:"},
+			wantSections: map[string][]string{"Below is the list of failed validators:": {"[warning] File: a"}},
+		},
+		{
+			name:       "tracked",
+			errs:       tracked,
+			wantTitles: []string{"New failed validators:", "Still outstanding:", "Resolved since the last run:"},
+			wantSections: map[string][]string{
+				"New failed validators:":       {"File: a"},
+				"Still outstanding:":           {"[warning] File: b (3 days)"},
+				"Resolved since the last run:": {"File: c"},
+			},
+			wantAckNote: "1 acknowledged errors are not listed.",
+		},
+		{
+			name:         "only resolved",
+			errs:         tracked[2:3],
+			wantTitles:   []string{"Resolved since the last run:"},
+			wantSections: map[string][]string{"Resolved since the last run:": {"File: c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := groupErrors(tt.errs, now)
+
+			var titles []string
+			sections := make(map[string][]string)
+			for _, section := range groups.Sections() {
+				titles = append(titles, section.Title)
+				for _, e := range section.Errors {
+					sections[section.Title] = append(sections[section.Title], e.Summary())
+				}
+			}
+			if !reflect.DeepEqual(titles, tt.wantTitles) || !reflect.DeepEqual(sections, tt.wantSections) {
+				t.Errorf("expected sections %v %v, got %v %v", tt.wantTitles, tt.wantSections, titles, sections)
+			}
+			if note := groups.AcknowledgedNote(); note != tt.wantAckNote {
+				t.Errorf("expected note %q, got %q", tt.wantAckNote, note)
+			}
+
+			// the email lists the same sections
+			content, err := formatEmailContent(tt.errs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, title := range tt.wantTitles {
+				if !strings.Contains(content, "<div>"+title+"</div>") {
+					t.Errorf("expected the email to contain %q", title)
+				}
+			}
+			if tt.wantAckNote != "" && !strings.Contains(content, tt.wantAckNote) {
+				t.Errorf("expected the email to contain %q", tt.wantAckNote)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package notifier
 
 import (
 	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/fixer"
 	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
 	"github.com/dkoshkin/invoices-validator/pkg/validator"
 	log "github.com/sirupsen/logrus"
@@ -16,8 +17,12 @@ const (
 
 	notifierSMSNumbersEnv = "NOTIFIER_SMS_PHONE_NUMBERS"
 
-	emailNotifier = "email"
-	smsNotifier   = "sms"
+	emailNotifier   = "email"
+	smsNotifier     = "sms"
+	slackNotifier   = "slack"
+	webhookNotifier = "webhook"
+	teamsNotifier   = "teams"
+	smtpNotifier    = "smtp"
 )
 
 var defaultEmailContactsGetter = func() []Contact {
@@ -32,11 +37,11 @@ var defaultEmailContactsGetter = func() []Contact {
 			log.Errorf("invalid name=email pair: %q", contact)
 			return
 		}
-		c := Contact{split[0], split[1]}
+		c := Contact{Name: split[0], Address: split[1]}
 		contacts = append(contacts, c)
 	})
 
-	return contacts
+	return withRoutes(contacts, notifierEmailRoutesEnv)
 }
 
 var defaultSMSContactGetter = func() []Contact {
@@ -46,19 +51,33 @@ var defaultSMSContactGetter = func() []Contact {
 		contacts = append(contacts, Contact{Address: number})
 	}
 
-	return contacts
+	return withRoutes(contacts, notifierSMSRoutesEnv)
 }
 
 type Notifier interface {
 	// SetContactsGetter allows to customize how the contacts are populated when Sending
 	SetContactsGetter(f func() []Contact)
+	// Contacts returns the contacts the notifier sends to
+	Contacts() []Contact
 	Send(subject string, content string) error
 	FormatContent(errs []validator.ValidationError) (string, error)
 }
 
+// RootSetter is implemented by notifiers that include the validated root path in their content
+type RootSetter interface {
+	SetRoot(root string)
+}
+
+// PlanFormatter is implemented by notifiers that can send the proposed renames for review
+type PlanFormatter interface {
+	FormatPlan(fixes []fixer.Fix) (string, error)
+}
+
 type Contact struct {
 	Name    string
 	Address string
+	// Folders are the path prefixes of the errors the contact is notified about, all errors when empty
+	Folders []string
 }
 
 func ConfiguredNotifiers() ([]Notifier, error) {
@@ -86,6 +105,30 @@ func ConfiguredNotifiers() ([]Notifier, error) {
 			} else {
 				notifiers = append(notifiers, smsNotifier)
 			}
+		case slackNotifier:
+			if slackNotifier, err := NewSlackNotifier(); err != nil {
+				log.Errorf("could not initialize Slack notifier: %v", err)
+			} else {
+				notifiers = append(notifiers, slackNotifier)
+			}
+		case webhookNotifier:
+			if webhookNotifier, err := NewWebhookNotifier(); err != nil {
+				log.Errorf("could not initialize webhook notifier: %v", err)
+			} else {
+				notifiers = append(notifiers, webhookNotifier)
+			}
+		case teamsNotifier:
+			if teamsNotifier, err := NewTeamsNotifier(); err != nil {
+				log.Errorf("could not initialize Teams notifier: %v", err)
+			} else {
+				notifiers = append(notifiers, teamsNotifier)
+			}
+		case smtpNotifier:
+			if smtpNotifier, err := NewSMTPNotifier(); err != nil {
+				log.Errorf("could not initialize SMTP notifier: %v", err)
+			} else {
+				notifiers = append(notifiers, smtpNotifier)
+			}
 		}
 	}
 
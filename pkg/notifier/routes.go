@@ -0,0 +1,170 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/fixer"
+	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	// notifierEmailRoutesEnv and notifierSMSRoutesEnv route the errors under folders to contacts,
+	// ie "john@example.com=/Invoices/John Doe|/Invoices/Jane Doe:jane@example.com=/Invoices/Jim Doe"
+	notifierEmailRoutesEnv = "NOTIFIER_EMAIL_ROUTES"
+	notifierSMSRoutesEnv   = "NOTIFIER_SMS_ROUTES"
+)
+
+// Routed returns true if the contact only receives the errors under its folders
+func (c Contact) Routed() bool {
+	return len(c.Folders) > 0
+}
+
+// Receives returns true if the contact is notified about errors at the path,
+// contacts without folders receive all the errors
+func (c Contact) Receives(p string) bool {
+	if !c.Routed() {
+		return true
+	}
+
+	lower := strings.ToLower(p)
+	for _, folder := range c.Folders {
+		folder = strings.TrimSuffix(strings.ToLower(path.Clean(folder)), "/")
+		if lower == folder || strings.HasPrefix(lower, folder+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// withRoutes sets the Folders of the contacts listed in the routes variable
+func withRoutes(contacts []Contact, routesEnv string) []Contact {
+	routes := make(map[string][]string)
+	for _, route := range stringsx.Split(os.Getenv(routesEnv), ":") {
+		split := stringsx.Split(route, "=")
+		if len(split) != 2 {
+			log.Errorf("invalid address=folders route: %q", route)
+			continue
+		}
+		address := strings.ToLower(split[0])
+		routes[address] = append(routes[address], stringsx.Split(split[1], "|")...)
+	}
+
+	matched := make(map[string]bool)
+	for i, contact := range contacts {
+		address := strings.ToLower(contact.Address)
+		contacts[i].Folders = routes[address]
+		matched[address] = true
+	}
+	// routes only restrict what the existing contacts receive, they don't add contacts
+	for address := range routes {
+		if !matched[address] {
+			log.Errorf("%s routes folders to %q which is not a contact", routesEnv, address)
+		}
+	}
+	return contacts
+}
+
+// Dispatch sends the errors with the notifier, contacts without folders get all the errors in one notification
+// and every routed contact gets a notification with only the errors under its folders.
+// Routed contacts are not notified when there are no errors to list under their folders
+func Dispatch(n Notifier, subject string, errs []validator.ValidationError) error {
+	return dispatch(n, subject, func(contact Contact) (string, bool, error) {
+		var contactErrs []validator.ValidationError
+		listed := 0
+		for _, e := range errs {
+			if !contact.Receives(e.Path) {
+				continue
+			}
+			contactErrs = append(contactErrs, e)
+			if !e.Acknowledged {
+				listed++
+			}
+		}
+		if contact.Routed() && listed == 0 {
+			return "", false, nil
+		}
+
+		content, err := n.FormatContent(contactErrs)
+		return content, true, err
+	})
+}
+
+// DispatchPlan sends the proposed renames for review with the notifier the same way as Dispatch,
+// routed contacts only get the renames of the files and folders under their folders
+func DispatchPlan(n Notifier, formatter PlanFormatter, subject string, fixes []fixer.Fix) error {
+	return dispatch(n, subject, func(contact Contact) (string, bool, error) {
+		var contactFixes []fixer.Fix
+		for _, fix := range fixes {
+			if contact.Receives(fix.From) {
+				contactFixes = append(contactFixes, fix)
+			}
+		}
+		if contact.Routed() && len(contactFixes) == 0 {
+			return "", false, nil
+		}
+
+		content, err := formatter.FormatPlan(contactFixes)
+		return content, true, err
+	})
+}
+
+// dispatch sends a notification to the contacts without folders and one to every routed contact,
+// format returns the content for a contact or false when the contact is not notified
+func dispatch(n Notifier, subject string, format func(contact Contact) (string, bool, error)) error {
+	contacts := n.Contacts()
+	// the getter is replaced for every notification, restore the contacts when done
+	defer n.SetContactsGetter(func() []Contact { return contacts })
+
+	var admins []Contact
+	var routed []Contact
+	for _, contact := range contacts {
+		if contact.Routed() {
+			routed = append(routed, contact)
+		} else {
+			admins = append(admins, contact)
+		}
+	}
+
+	var failed []string
+	if len(admins) > 0 || len(routed) == 0 {
+		if err := send(n, admins, subject, format); err != nil {
+			log.Errorf("could not send notification: %v", err)
+			failed = append(failed, "admins")
+		}
+	}
+
+	for _, contact := range routed {
+		if err := send(n, []Contact{contact}, subject, format); err != nil {
+			log.Errorf("could not send notification to %q: %v", contact.Address, err)
+			failed = append(failed, contact.Address)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not notify %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// send formats the content for the first contact, contacts are either all admins or a single routed contact
+func send(n Notifier, contacts []Contact, subject string, format func(contact Contact) (string, bool, error)) error {
+	var contact Contact
+	if len(contacts) > 0 {
+		contact = contacts[0]
+	}
+	content, ok, err := format(contact)
+	if err != nil {
+		return fmt.Errorf("could not format content: %v", err)
+	}
+	if !ok {
+		log.Debugf("Nothing to notify %q about under its folders", contact.Address)
+		return nil
+	}
+
+	n.SetContactsGetter(func() []Contact { return contacts })
+	return n.Send(subject, content)
+}
@@ -0,0 +1,150 @@
+package notifier
+
+import (
+	"github.com/dkoshkin/invoices-validator/pkg/fixer"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContactReceives(t *testing.T) {
+	tests := []struct {
+		name    string
+		folders []string
+		path    string
+		want    bool
+	}{
+		{name: "not routed", path: "/Invoices/Jane Doe/013119-01.docx", want: true},
+		{name: "under the folder", folders: []string{"/Invoices/John"}, path: "/Invoices/John/013119-01.docx", want: true},
+		{name: "the folder itself", folders: []string{"/Invoices/John"}, path: "/Invoices/John", want: true},
+		{name: "different case", folders: []string{"/invoices/JOHN"}, path: "/Invoices/John/013119-01.docx", want: true},
+		{name: "trailing slash", folders: []string{"/Invoices/John/"}, path: "/Invoices/John/013119-01.docx", want: true},
+		{name: "folder name prefix", folders: []string{"/Invoices/John"}, path: "/Invoices/Johnny/013119-01.docx", want: false},
+		{name: "parent folder", folders: []string{"/Invoices/John"}, path: "/Invoices", want: false},
+		{name: "sequence error", folders: []string{"/Invoices/John"}, path: "/Invoices/John/013119*", want: true},
+		{name: "second folder", folders: []string{"/Invoices/Jane", "/Invoices/John"}, path: "/Invoices/John/x.docx", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Contact{Address: "john@example.com", Folders: tt.folders}
+			if got := c.Receives(tt.path); got != tt.want {
+				t.Errorf("Receives(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRoutes(t *testing.T) {
+	os.Setenv(notifierEmailRoutesEnv, "John@example.com=/Invoices/John Doe|/Invoices/Doe, Jane:unknown@example.com=/Invoices/Jim")
+	defer os.Unsetenv(notifierEmailRoutesEnv)
+
+	contacts := withRoutes([]Contact{{Address: "admin@example.com"}, {Address: "john@example.com"}}, notifierEmailRoutesEnv)
+	if contacts[0].Routed() {
+		t.Errorf("admin should not be routed, got %v", contacts[0].Folders)
+	}
+	if want := []string{"/Invoices/John Doe", "/Invoices/Doe, Jane"}; !reflect.DeepEqual(contacts[1].Folders, want) {
+		t.Errorf("expected folders %v, got %v", want, contacts[1].Folders)
+	}
+}
+
+// fakeNotifier records the content sent to every contact
+type fakeNotifier struct {
+	contacts func() []Contact
+	sent     map[string]string
+}
+
+func (n *fakeNotifier) SetContactsGetter(f func() []Contact) {
+	n.contacts = f
+}
+
+func (n *fakeNotifier) Contacts() []Contact {
+	return n.contacts()
+}
+
+func (n *fakeNotifier) Send(subject string, content string) error {
+	for _, contact := range n.contacts() {
+		n.sent[contact.Address] = content
+	}
+	return nil
+}
+
+func (n *fakeNotifier) FormatContent(errs []validator.ValidationError) (string, error) {
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	return strings.Join(paths, ","), nil
+}
+
+func (n *fakeNotifier) FormatPlan(fixes []fixer.Fix) (string, error) {
+	var paths []string
+	for _, fix := range fixes {
+		paths = append(paths, fix.From)
+	}
+	return strings.Join(paths, ","), nil
+}
+
+func newFakeNotifier() *fakeNotifier {
+	contacts := []Contact{
+		{Address: "admin@example.com"},
+		{Address: "john@example.com", Folders: []string{"/Invoices/John"}},
+		{Address: "jane@example.com", Folders: []string{"/Invoices/Jane"}},
+		{Address: "jim@example.com", Folders: []string{"/Invoices/Jim"}},
+	}
+	return &fakeNotifier{
+		contacts: func() []Contact { return contacts },
+		sent:     make(map[string]string),
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	n := newFakeNotifier()
+	errs := []validator.ValidationError{
+		{Path: "/Invoices/John/1.docx"},
+		{Path: "/Invoices/Johnny/1.docx"},
+		{Path: "/Invoices/Jane/1.docx", Acknowledged: true},
+		{Path: "/Invoices/Jim/1.docx", Acknowledged: true},
+		{Path: "/Invoices/Jim/2.docx", Status: validator.StatusResolved},
+	}
+
+	if err := Dispatch(n, "subject", errs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"admin@example.com": "/Invoices/John/1.docx,/Invoices/Johnny/1.docx,/Invoices/Jane/1.docx,/Invoices/Jim/1.docx,/Invoices/Jim/2.docx",
+		"john@example.com":  "/Invoices/John/1.docx",
+		// jane only has acknowledged errors and is not notified, resolved errors are listed
+		"jim@example.com": "/Invoices/Jim/1.docx,/Invoices/Jim/2.docx",
+	}
+	if !reflect.DeepEqual(n.sent, want) {
+		t.Errorf("expected %v, got %v", want, n.sent)
+	}
+	if len(n.Contacts()) != 4 {
+		t.Errorf("expected the contacts to be restored, got %v", n.Contacts())
+	}
+}
+
+func TestDispatchPlan(t *testing.T) {
+	n := newFakeNotifier()
+	fixes := []fixer.Fix{
+		{From: "/Invoices/John/1-31-19-1.docx"},
+		{From: "/Invoices/Jane/013119_01.docx"},
+	}
+
+	if err := DispatchPlan(n, n, "subject", fixes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"admin@example.com": "/Invoices/John/1-31-19-1.docx,/Invoices/Jane/013119_01.docx",
+		"john@example.com":  "/Invoices/John/1-31-19-1.docx",
+		"jane@example.com":  "/Invoices/Jane/013119_01.docx",
+	}
+	if !reflect.DeepEqual(n.sent, want) {
+		t.Errorf("expected %v, got %v", want, n.sent)
+	}
+}
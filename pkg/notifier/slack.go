@@ -0,0 +1,280 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	slackWebhookURLEnv = "SLACK_WEBHOOK_URL"
+	slackTokenEnv      = "SLACK_TOKEN"
+	// slackEndpointEnv overrides the Slack API endpoint, ie to point to a test server
+	slackEndpointEnv = "SLACK_ENDPOINT"
+
+	notifierSlackChannelsEnv = "NOTIFIER_SLACK_CHANNELS"
+
+	defaultSlackEndpoint = "https://slack.com/api"
+
+	// Slack rejects messages with more blocks and truncates longer texts
+	slackMaxBlocks      = 50
+	slackMaxTextLength  = 3000
+	slackMaxHeaderChars = 150
+)
+
+var defaultSlackContactsGetter = func() []Contact {
+	channels := os.Getenv(notifierSlackChannelsEnv)
+	contacts := make([]Contact, 0)
+	for _, channel := range stringsx.Split(channels, ":") {
+		contacts = append(contacts, Contact{Address: channel})
+	}
+
+	return contacts
+}
+
+type slackChatNotifier struct {
+	client *http.Client
+	// webhookURL is set when posting with an incoming webhook, the channel is then configured in Slack
+	webhookURL string
+	// token and endpoint are set when posting with chat.postMessage to the contacts channels
+	token    string
+	endpoint string
+
+	contacts func() []Contact
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackMessage struct {
+	Channel string       `json:"channel,omitempty"`
+	Text    string       `json:"text"`
+	Blocks  []slackBlock `json:"blocks"`
+}
+
+type slackResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// NewSlackNotifier posts to SLACK_WEBHOOK_URL when set,
+// otherwise to the NOTIFIER_SLACK_CHANNELS with chat.postMessage using SLACK_TOKEN
+func NewSlackNotifier() (Notifier, error) {
+	log.Info("Initializing Slack notifier...")
+
+	webhookURL := os.Getenv(slackWebhookURLEnv)
+	token := os.Getenv(slackTokenEnv)
+	if webhookURL == "" && token == "" {
+		return nil, fmt.Errorf("%s or %s variable must be set", slackWebhookURLEnv, slackTokenEnv)
+	}
+
+	endpoint := os.Getenv(slackEndpointEnv)
+	if endpoint == "" {
+		endpoint = defaultSlackEndpoint
+	}
+
+	notifier := newSlackNotifier(&http.Client{Timeout: 30 * time.Second}, webhookURL, token, endpoint)
+	log.Info("Slack notifier initialized successfully")
+
+	return notifier, nil
+}
+
+func newSlackNotifier(client *http.Client, webhookURL, token, endpoint string) *slackChatNotifier {
+	return &slackChatNotifier{
+		client:     client,
+		webhookURL: webhookURL,
+		token:      token,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		contacts:   defaultSlackContactsGetter,
+	}
+}
+
+func (n *slackChatNotifier) SetContactsGetter(f func() []Contact) {
+	n.contacts = f
+}
+
+func (n slackChatNotifier) Contacts() []Contact {
+	return n.contacts()
+}
+
+// Send posts the blocks of FormatContent under a header with the subject,
+// split over as many messages as needed to respect the Slack limits
+func (n slackChatNotifier) Send(subject string, content string) error {
+	log.Info("Notifying using Slack notifier...")
+
+	var blocks []slackBlock
+	if err := json.Unmarshal([]byte(content), &blocks); err != nil {
+		return fmt.Errorf("invalid Slack content: %v", err)
+	}
+	header := slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: truncate(subject, slackMaxHeaderChars)}}
+	messages := slackMessages(subject, append([]slackBlock{header}, blocks...))
+
+	if n.webhookURL != "" {
+		for _, message := range messages {
+			if err := n.postWebhook(message); err != nil {
+				return fmt.Errorf("error sending Slack message: %v", err)
+			}
+		}
+		log.Info("Slack message sent successfully to the webhook")
+		return nil
+	}
+
+	contacts := n.contacts()
+	if len(contacts) == 0 {
+		return fmt.Errorf("empty Slack channel list to send to")
+	}
+	for _, contact := range contacts {
+		for _, message := range messages {
+			message.Channel = contact.Address
+			if err := n.postMessage(message); err != nil {
+				return fmt.Errorf("error sending Slack message to %q: %v", contact.Address, err)
+			}
+		}
+		log.Infof("Slack message sent successfully to: %q", contact.Address)
+	}
+
+	return nil
+}
+
+// FormatContent returns the JSON Block Kit blocks of the errors, one section per error
+func (n slackChatNotifier) FormatContent(errs []validator.ValidationError) (string, error) {
+	groups := groupErrors(errs, time.Now())
+
+	var blocks []slackBlock
+	for _, section := range groups.Sections() {
+		blocks = append(blocks, slackErrorBlocks(section.Title, section.Errors)...)
+	}
+	if note := groups.AcknowledgedNote(); note != "" {
+		blocks = append(blocks, slackBlock{
+			Type:     "context",
+			Elements: []slackText{{Type: "mrkdwn", Text: note}},
+		})
+	}
+
+	content, err := json.Marshal(blocks)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal Slack blocks: %v", err)
+	}
+
+	return string(content), nil
+}
+
+func slackErrorBlocks(title string, errs []groupedError) []slackBlock {
+	blocks := []slackBlock{slackSection(fmt.Sprintf("*%s*", slackEscape(title)))}
+	for _, e := range errs {
+		text := fmt.Sprintf("*%s*", slackEscape(e.Summary()))
+		text = fmt.Sprintf("%s\n*Path:* %s\n*Actual:* %s\n*Expected:* %s\n*Rule:* %s",
+			text, slackEscape(e.Path), slackEscape(e.Actual), slackEscape(e.Expected), slackEscape(e.Rule))
+		blocks = append(blocks, slackSection(text))
+	}
+	return blocks
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(text, slackMaxTextLength)}}
+}
+
+// slackMessages splits the blocks in messages of at most slackMaxBlocks blocks
+func slackMessages(subject string, blocks []slackBlock) []slackMessage {
+	var messages []slackMessage
+	for len(blocks) > 0 {
+		size := slackMaxBlocks
+		if len(blocks) < size {
+			size = len(blocks)
+		}
+		messages = append(messages, slackMessage{Text: subject, Blocks: blocks[:size]})
+		blocks = blocks[size:]
+	}
+
+	if len(messages) > 1 {
+		for i := range messages {
+			messages[i].Text = fmt.Sprintf("%s (%d/%d)", subject, i+1, len(messages))
+		}
+	}
+	return messages
+}
+
+func (n slackChatNotifier) postWebhook(message slackMessage) error {
+	resp, err := n.post(n.webhookURL, message)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected response status %q: %s", resp.Status, body)
+	}
+	return nil
+}
+
+func (n slackChatNotifier) postMessage(message slackMessage) error {
+	resp, err := n.post(n.endpoint+"/chat.postMessage", message)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	// the API responds with a 200 even when the message was not posted
+	slackResp := slackResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&slackResp); err != nil {
+		return fmt.Errorf("could not decode response: %v", err)
+	}
+	if !slackResp.OK {
+		return fmt.Errorf("slack error %q", slackResp.Error)
+	}
+	return nil
+}
+
+func (n slackChatNotifier) post(url string, message slackMessage) (*http.Response, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal message: %v", err)
+	}
+	log.Debugf("Slack Request:\n%s", body)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if n.token != "" && n.webhookURL == "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	return n.client.Do(req)
+}
+
+// slackEscape escapes the control characters of Slack mrkdwn
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncate shortens s to at most max runes
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,169 @@
+package notifier
+
+import (
+	"encoding/json"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// slackServer records the messages posted to it and responds with response
+type slackServer struct {
+	*httptest.Server
+
+	mu             sync.Mutex
+	paths          []string
+	authorizations []string
+	messages       []slackMessage
+}
+
+func newSlackServer(t *testing.T, response string) *slackServer {
+	s := &slackServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Errorf("invalid message: %v", err)
+		}
+		s.mu.Lock()
+		s.paths = append(s.paths, r.URL.Path)
+		s.authorizations = append(s.authorizations, r.Header.Get("Authorization"))
+		s.messages = append(s.messages, message)
+		s.mu.Unlock()
+		w.Write([]byte(response))
+	}))
+	return s
+}
+
+func slackTestContent(t *testing.T, n *slackChatNotifier) string {
+	content, err := n.FormatContent([]validator.ValidationError{{
+		Actual:         "1-31-19.docx",
+		Expected:       "MMDDYY-NN.docx",
+		AdditionalInfo: "Invalid file name",
+		Rule:           "file-name",
+		Path:           "/Invoices/John Doe/1-31-19.docx",
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return content
+}
+
+func TestSlackNotifierSendWebhook(t *testing.T) {
+	server := newSlackServer(t, "ok")
+	defer server.Close()
+
+	n := newSlackNotifier(server.Client(), server.URL+"/services/T000/B000/XXXX", "", defaultSlackEndpoint)
+	// the channels are ignored when posting to a webhook
+	n.SetContactsGetter(func() []Contact { return []Contact{{Address: "#invoices"}} })
+
+	if err := n.Send("Failed Invoice Validations – 01312019", slackTestContent(t, n)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(server.messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(server.messages))
+	}
+	if server.paths[0] != "/services/T000/B000/XXXX" || server.authorizations[0] != "" {
+		t.Errorf("unexpected request to %q with authorization %q", server.paths[0], server.authorizations[0])
+	}
+	message := server.messages[0]
+	if message.Channel != "" || message.Text != "Failed Invoice Validations – 01312019" {
+		t.Errorf("unexpected message: %+v", message)
+	}
+	if len(message.Blocks) != 3 || message.Blocks[0].Type != "header" ||
+		!strings.Contains(message.Blocks[2].Text.Text, "*Path:* /Invoices/John Doe/1-31-19.docx") {
+		t.Errorf("unexpected blocks: %+v", message.Blocks)
+	}
+}
+
+func TestSlackNotifierSendWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	n := newSlackNotifier(server.Client(), server.URL, "", defaultSlackEndpoint)
+	err := n.Send("subject", slackTestContent(t, n))
+	if err == nil || !strings.Contains(err.Error(), "invalid_payload") {
+		t.Errorf("expected the response body in the error, got %v", err)
+	}
+}
+
+func TestSlackNotifierSendPostMessage(t *testing.T) {
+	server := newSlackServer(t, `{"ok":true}`)
+	defer server.Close()
+
+	n := newSlackNotifier(server.Client(), "", "xoxb-token", server.URL+"/")
+	n.SetContactsGetter(func() []Contact { return []Contact{{Address: "#invoices"}, {Address: "C024BE91L"}} })
+
+	if err := n.Send("subject", slackTestContent(t, n)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(server.messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(server.messages))
+	}
+	for i, channel := range []string{"#invoices", "C024BE91L"} {
+		if server.paths[i] != "/chat.postMessage" || server.authorizations[i] != "Bearer xoxb-token" {
+			t.Errorf("unexpected request to %q with authorization %q", server.paths[i], server.authorizations[i])
+		}
+		if server.messages[i].Channel != channel {
+			t.Errorf("expected channel %q, got %q", channel, server.messages[i].Channel)
+		}
+	}
+}
+
+func TestSlackNotifierSendPostMessageNotOK(t *testing.T) {
+	server := newSlackServer(t, `{"ok":false,"error":"channel_not_found"}`)
+	defer server.Close()
+
+	n := newSlackNotifier(server.Client(), "", "xoxb-token", server.URL)
+	n.SetContactsGetter(func() []Contact { return []Contact{{Address: "#unknown"}, {Address: "#invoices"}} })
+
+	err := n.Send("subject", slackTestContent(t, n))
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") || !strings.Contains(err.Error(), "#unknown") {
+		t.Errorf("expected a channel_not_found error for #unknown, got %v", err)
+	}
+	// the API responds with a 200, the error must still stop the notifier
+	if len(server.messages) != 1 {
+		t.Errorf("expected to stop after the first message, got %d", len(server.messages))
+	}
+}
+
+func TestSlackMessages(t *testing.T) {
+	tests := []struct {
+		blocks     int
+		wantBlocks []int
+		wantTexts  []string
+	}{
+		{blocks: 1, wantBlocks: []int{1}, wantTexts: []string{"subject"}},
+		{blocks: slackMaxBlocks, wantBlocks: []int{50}, wantTexts: []string{"subject"}},
+		{blocks: slackMaxBlocks + 1, wantBlocks: []int{50, 1}, wantTexts: []string{"subject (1/2)", "subject (2/2)"}},
+		{blocks: 2 * slackMaxBlocks, wantBlocks: []int{50, 50}, wantTexts: []string{"subject (1/2)", "subject (2/2)"}},
+	}
+
+	for _, tt := range tests {
+		blocks := make([]slackBlock, tt.blocks)
+		for i := range blocks {
+			blocks[i] = slackSection(string(rune('a' + i%26)))
+		}
+
+		messages := slackMessages("subject", blocks)
+		if len(messages) != len(tt.wantBlocks) {
+			t.Fatalf("%d blocks: expected %d messages, got %d", tt.blocks, len(tt.wantBlocks), len(messages))
+		}
+		for i, message := range messages {
+			if len(message.Blocks) != tt.wantBlocks[i] || message.Text != tt.wantTexts[i] {
+				t.Errorf("%d blocks: message %d has %d blocks and text %q, expected %d and %q",
+					tt.blocks, i, len(message.Blocks), message.Text, tt.wantBlocks[i], tt.wantTexts[i])
+			}
+		}
+		// the blocks are kept in order across the messages
+		if last := messages[len(messages)-1].Blocks; last[len(last)-1].Text != blocks[len(blocks)-1].Text {
+			t.Errorf("%d blocks: expected the last block to be sent last", tt.blocks)
+		}
+	}
+}
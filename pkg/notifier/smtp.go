@@ -0,0 +1,333 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/fixer"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	smtpHostEnv     = "SMTP_HOST"
+	smtpPortEnv     = "SMTP_PORT"
+	smtpUsernameEnv = "SMTP_USERNAME"
+	smtpPasswordEnv = "SMTP_PASSWORD"
+	// smtpTLSEnv is one of the smtpTLS* modes, defaults to smtpTLSStartTLS
+	smtpTLSEnv = "SMTP_TLS"
+	// smtpAuthEnv is one of the smtpAuth* mechanisms, defaults to smtpAuthPlain
+	smtpAuthEnv = "SMTP_AUTH"
+
+	// smtpTLSStartTLS upgrades the connection with STARTTLS, usually on port 587
+	smtpTLSStartTLS = "starttls"
+	// smtpTLSImplicit connects with TLS, usually on port 465
+	smtpTLSImplicit = "implicit"
+	// smtpTLSNone doesn't encrypt the connection, only for relays on a trusted network
+	smtpTLSNone = "none"
+
+	smtpAuthPlain = "plain"
+	smtpAuthLogin = "login"
+
+	smtpTimeout = 30 * time.Second
+)
+
+var defaultSMTPPorts = map[string]string{
+	smtpTLSStartTLS: "587",
+	smtpTLSImplicit: "465",
+	smtpTLSNone:     "25",
+}
+
+type smtpMailNotifier struct {
+	host     string
+	port     string
+	username string
+	password string
+	tls      string
+	auth     string
+	// tlsConfig is used for both STARTTLS and implicit TLS
+	tlsConfig *tls.Config
+
+	senderName  string
+	senderEmail string
+
+	contacts func() []Contact
+}
+
+// NewSMTPNotifier sends the same emails as the SendGrid notifier through the mail server at SMTP_HOST
+func NewSMTPNotifier() (Notifier, error) {
+	log.Info("Initializing SMTP notifier...")
+	notifier, err := newSMTPNotifier(smtpMailNotifier{
+		host:        os.Getenv(smtpHostEnv),
+		port:        os.Getenv(smtpPortEnv),
+		username:    os.Getenv(smtpUsernameEnv),
+		password:    os.Getenv(smtpPasswordEnv),
+		tls:         strings.ToLower(os.Getenv(smtpTLSEnv)),
+		auth:        strings.ToLower(os.Getenv(smtpAuthEnv)),
+		senderName:  os.Getenv(notifierSenderNameEnv),
+		senderEmail: os.Getenv(notifierSenderEmailEnv),
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("SMTP notifier initialized successfully")
+
+	return notifier, nil
+}
+
+// newSMTPNotifier validates the settings and sets the defaults of the notifier,
+// tlsConfig defaults to verifying the certificate of the host
+func newSMTPNotifier(notifier smtpMailNotifier, tlsConfig *tls.Config) (*smtpMailNotifier, error) {
+	for env, val := range map[string]string{
+		smtpHostEnv:            notifier.host,
+		notifierSenderNameEnv:  notifier.senderName,
+		notifierSenderEmailEnv: notifier.senderEmail,
+	} {
+		if val == "" {
+			return nil, fmt.Errorf("%s variable must be set", env)
+		}
+	}
+
+	if notifier.tls == "" {
+		notifier.tls = smtpTLSStartTLS
+	}
+	if _, ok := defaultSMTPPorts[notifier.tls]; !ok {
+		return nil, fmt.Errorf("%s must be one of %q, %q or %q", smtpTLSEnv, smtpTLSStartTLS, smtpTLSImplicit, smtpTLSNone)
+	}
+	if notifier.port == "" {
+		notifier.port = defaultSMTPPorts[notifier.tls]
+	}
+
+	if notifier.auth == "" {
+		notifier.auth = smtpAuthPlain
+	}
+	if notifier.auth != smtpAuthPlain && notifier.auth != smtpAuthLogin {
+		return nil, fmt.Errorf("%s must be one of %q or %q", smtpAuthEnv, smtpAuthPlain, smtpAuthLogin)
+	}
+	if (notifier.username == "") != (notifier.password == "") {
+		return nil, fmt.Errorf("%s and %s must be set together", smtpUsernameEnv, smtpPasswordEnv)
+	}
+
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: notifier.host}
+	}
+	notifier.tlsConfig = tlsConfig
+	if notifier.contacts == nil {
+		notifier.contacts = defaultEmailContactsGetter
+	}
+
+	return &notifier, nil
+}
+
+func (n *smtpMailNotifier) SetContactsGetter(f func() []Contact) {
+	n.contacts = f
+}
+
+func (n smtpMailNotifier) Contacts() []Contact {
+	return n.contacts()
+}
+
+// Send sends the HTML content with a plain text alternative for the clients that don't display HTML
+func (n smtpMailNotifier) Send(subject string, content string) error {
+	log.Info("Notifying using SMTP notifier...")
+	contacts := n.contacts()
+	if len(contacts) == 0 {
+		return fmt.Errorf("empty email list to send to")
+	}
+
+	message, err := n.message(subject, content, contacts)
+	if err != nil {
+		return fmt.Errorf("could not create email: %v", err)
+	}
+	log.Debugf("Email Request:\n%s", message)
+
+	if err := n.send(contacts, message); err != nil {
+		return fmt.Errorf("error sending email: %v", err)
+	}
+
+	log.Infof("Email sent successfully to: %v", contacts)
+
+	return nil
+}
+
+func (n smtpMailNotifier) FormatContent(errs []validator.ValidationError) (string, error) {
+	return formatEmailContent(errs)
+}
+
+func (n smtpMailNotifier) FormatPlan(fixes []fixer.Fix) (string, error) {
+	return formatEmailPlan(fixes)
+}
+
+func (n smtpMailNotifier) send(contacts []Contact, message []byte) error {
+	address := net.JoinHostPort(n.host, n.port)
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	if n.tls == smtpTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, n.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	// the deadline covers the whole conversation, net/smtp has no timeouts of its own
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if n.tls == smtpTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("server does not support STARTTLS, set %s=%s if it only accepts implicit TLS", smtpTLSEnv, smtpTLSImplicit)
+		}
+		if err := client.StartTLS(n.tlsConfig); err != nil {
+			return fmt.Errorf("could not start TLS: %v", err)
+		}
+	}
+
+	if n.username != "" {
+		auth := smtp.PlainAuth("", n.username, n.password, n.host)
+		if n.auth == smtpAuthLogin {
+			auth = &loginAuth{username: n.username, password: n.password, host: n.host}
+		}
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("could not authenticate: %v", err)
+		}
+	}
+
+	if err := client.Mail(n.senderEmail); err != nil {
+		return err
+	}
+	for _, contact := range contacts {
+		if err := client.Rcpt(contact.Address); err != nil {
+			return fmt.Errorf("recipient %q: %v", contact.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// message returns a multipart/alternative email with a plain text and an HTML part
+func (n smtpMailNotifier) message(subject string, content string, contacts []Contact) ([]byte, error) {
+	text, err := htmlToText(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", content},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	to := make([]string, 0, len(contacts))
+	for _, contact := range contacts {
+		to = append(to, (&mail.Address{Name: contact.Name, Address: contact.Address}).String())
+	}
+
+	var message bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", (&mail.Address{Name: n.senderName, Address: n.senderEmail}).String()},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary())},
+	} {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// loginAuth implements the LOGIN mechanism not supported by net/smtp, used by older Exchange and Office 365 servers
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// same as smtp.PlainAuth, never send the password over an unencrypted connection to a remote server
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,278 @@
+package notifier
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTPServer accepts a single SMTP conversation and records it
+type fakeSMTPServer struct {
+	listener net.Listener
+	implicit bool
+	config   *tls.Config
+
+	mu sync.Mutex
+	// commands are the commands sent by the client, tls is true if the conversation was encrypted when the mail was sent
+	commands []string
+	login    []string
+	data     string
+	tls      bool
+	done     chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T, cert tls.Certificate, implicit bool) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{
+		implicit: implicit,
+		config:   &tls.Config{Certificates: []tls.Certificate{cert}},
+		done:     make(chan struct{}),
+	}
+	s.listener = listener
+	if implicit {
+		s.listener = tls.NewListener(listener, s.config)
+	}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	encrypted := s.implicit
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+	read := func() string {
+		line, _ := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n")
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line := read()
+		if line == "" {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+		case "EHLO":
+			reply("250-fake")
+			if !encrypted {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.config)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r, encrypted = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			if strings.Contains(strings.ToUpper(line), "LOGIN") {
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+				username, _ := base64.StdEncoding.DecodeString(read())
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+				password, _ := base64.StdEncoding.DecodeString(read())
+				s.mu.Lock()
+				s.login = []string{string(username), string(password)}
+				s.mu.Unlock()
+			}
+			reply("235 authenticated")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for line := read(); line != "."; line = read() {
+				data.WriteString(line + "\r\n")
+			}
+			s.mu.Lock()
+			s.data, s.tls = data.String(), encrypted
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	// reuse the self-signed certificate of httptest, valid for 127.0.0.1
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	tests := []struct {
+		tls       string
+		auth      string
+		wantAuth  string
+		wantLogin []string
+	}{
+		{tls: smtpTLSStartTLS, auth: smtpAuthPlain, wantAuth: "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00user\x00secret"))},
+		{tls: smtpTLSStartTLS, auth: smtpAuthLogin, wantAuth: "AUTH LOGIN", wantLogin: []string{"user", "secret"}},
+		{tls: smtpTLSImplicit, auth: smtpAuthPlain, wantAuth: "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00user\x00secret"))},
+		{tls: smtpTLSImplicit, auth: smtpAuthLogin, wantAuth: "AUTH LOGIN", wantLogin: []string{"user", "secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tls+"-"+tt.auth, func(t *testing.T) {
+			fake := newFakeSMTPServer(t, server.TLS.Certificates[0], tt.tls == smtpTLSImplicit)
+			defer fake.listener.Close()
+			host, port, _ := net.SplitHostPort(fake.listener.Addr().String())
+
+			n, err := newSMTPNotifier(smtpMailNotifier{
+				host:        host,
+				port:        port,
+				username:    "user",
+				password:    "secret",
+				tls:         tt.tls,
+				auth:        tt.auth,
+				senderName:  "Invoice Validator Bot",
+				senderEmail: "bot@example.com",
+				contacts: func() []Contact {
+					return []Contact{{Name: "John Doe", Address: "john@example.com"}, {Address: "jane@example.com"}}
+				},
+			}, &tls.Config{RootCAs: roots, ServerName: host})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content, err := n.FormatContent([]validator.ValidationError{{
+				Actual:         `"1-31-19.docx"`,
+				Expected:       "MMDDYY-NN.docx",
+				AdditionalInfo: "Invalid file name",
+				Rule:           "file-name",
+				Path:           "/Invoices/John Doe/1-31-19.docx",
+			}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := n.Send("Failed Invoice Validations – 01312019", content); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			<-fake.done
+
+			if !fake.tls {
+				t.Error("expected the mail to be sent over TLS")
+			}
+			if !contains(fake.commands, tt.wantAuth) {
+				t.Errorf("expected %q in %v", tt.wantAuth, fake.commands)
+			}
+			if tt.wantLogin != nil && strings.Join(fake.login, ":") != strings.Join(tt.wantLogin, ":") {
+				t.Errorf("expected login %v, got %v", tt.wantLogin, fake.login)
+			}
+			for _, want := range []string{"MAIL FROM:<bot@example.com>", "RCPT TO:<john@example.com>", "RCPT TO:<jane@example.com>"} {
+				if !contains(fake.commands, want) {
+					t.Errorf("expected %q in %v", want, fake.commands)
+				}
+			}
+
+			checkMultipartEmail(t, fake.data)
+		})
+	}
+}
+
+func checkMultipartEmail(t *testing.T, data string) {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid email: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Failed Invoice Validations – 01312019" {
+		t.Errorf("unexpected subject %q: %v", subject, err)
+	}
+	if to := msg.Header.Get("To"); to != `"John Doe" <john@example.com>, <jane@example.com>` {
+		t.Errorf("unexpected To %q", to)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q: %v", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	bodies := make(map[string]string)
+	for {
+		part, err := parts.NextPart()
+		if err != nil {
+			break
+		}
+		// the reader decodes quoted-printable parts
+		body, _ := ioutil.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = strings.ReplaceAll(string(body), "\r\n", "\n")
+	}
+
+	if text := bodies["text/plain"]; !strings.Contains(text, "Invalid file name\nActual: \"1-31-19.docx\"\nExpected: MMDDYY-NN.docx") ||
+		strings.Contains(text, "<") {
+		t.Errorf("unexpected text part:\n%s", text)
+	}
+	if html := bodies["text/html"]; !strings.Contains(html, "<span style=\"padding-left: 20px\">Expected: MMDDYY-NN.docx</span>") {
+		t.Errorf("unexpected HTML part:\n%s", html)
+	}
+}
+
+func TestNewSMTPNotifier(t *testing.T) {
+	valid := smtpMailNotifier{host: "mail.example.com", senderName: "Bot", senderEmail: "bot@example.com"}
+
+	n, err := newSMTPNotifier(valid, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.tls != smtpTLSStartTLS || n.port != "587" || n.auth != smtpAuthPlain || n.tlsConfig.ServerName != "mail.example.com" {
+		t.Errorf("unexpected defaults: %+v", n)
+	}
+
+	implicit := valid
+	implicit.tls = smtpTLSImplicit
+	if n, err := newSMTPNotifier(implicit, nil); err != nil || n.port != "465" {
+		t.Errorf("expected port 465 for implicit TLS, got %v", err)
+	}
+
+	for name, invalid := range map[string]func(n *smtpMailNotifier){
+		"missing host":     func(n *smtpMailNotifier) { n.host = "" },
+		"unknown TLS mode": func(n *smtpMailNotifier) { n.tls = "ssl" },
+		"unknown auth":     func(n *smtpMailNotifier) { n.auth = "cram-md5" },
+		"missing password": func(n *smtpMailNotifier) { n.username = "user" },
+	} {
+		settings := valid
+		invalid(&settings)
+		if _, err := newSMTPNotifier(settings, nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func contains(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
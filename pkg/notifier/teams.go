@@ -0,0 +1,237 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// notifierTeamsWebhookURLsEnv is "," separated as URLs contain ":"
+	notifierTeamsWebhookURLsEnv = "NOTIFIER_TEAMS_WEBHOOK_URLS"
+
+	adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+	adaptiveCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion     = "1.2"
+
+	// Teams rejects webhook messages larger than 28KB, keep some room for the envelope
+	teamsMaxCardBytes = 24 * 1024
+)
+
+var defaultTeamsContactsGetter = func() []Contact {
+	urls := os.Getenv(notifierTeamsWebhookURLsEnv)
+	contacts := make([]Contact, 0)
+	for _, url := range stringsx.Split(urls, ",") {
+		contacts = append(contacts, Contact{Address: url})
+	}
+
+	return contacts
+}
+
+type teamsWebhookNotifier struct {
+	client *http.Client
+
+	contacts func() []Contact
+}
+
+// cardElement is an Adaptive Card element, only the fields used by the notifications are listed
+type cardElement struct {
+	Type   string        `json:"type"`
+	Text   string        `json:"text,omitempty"`
+	Weight string        `json:"weight,omitempty"`
+	Size   string        `json:"size,omitempty"`
+	Wrap   bool          `json:"wrap,omitempty"`
+	Facts  []cardFact    `json:"facts,omitempty"`
+	Items  []cardElement `json:"items,omitempty"`
+}
+
+type cardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []cardElement `json:"body"`
+}
+
+// NewTeamsNotifier posts Adaptive Cards to the Microsoft Teams incoming webhooks at NOTIFIER_TEAMS_WEBHOOK_URLS
+func NewTeamsNotifier() (Notifier, error) {
+	log.Info("Initializing Teams notifier...")
+
+	if os.Getenv(notifierTeamsWebhookURLsEnv) == "" {
+		return nil, fmt.Errorf("%s variable must be set", notifierTeamsWebhookURLsEnv)
+	}
+
+	notifier := &teamsWebhookNotifier{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		contacts: defaultTeamsContactsGetter,
+	}
+	log.Info("Teams notifier initialized successfully")
+
+	return notifier, nil
+}
+
+func (n *teamsWebhookNotifier) SetContactsGetter(f func() []Contact) {
+	n.contacts = f
+}
+
+func (n teamsWebhookNotifier) Contacts() []Contact {
+	return n.contacts()
+}
+
+// Send posts the card elements of FormatContent under a title with the subject,
+// split over as many cards as needed to respect the Teams message size limit
+func (n teamsWebhookNotifier) Send(subject string, content string) error {
+	log.Info("Notifying using Teams notifier...")
+	contacts := n.contacts()
+	if len(contacts) == 0 {
+		return fmt.Errorf("empty Teams webhook URL list to send to")
+	}
+
+	var elements []cardElement
+	if err := json.Unmarshal([]byte(content), &elements); err != nil {
+		return fmt.Errorf("invalid Teams content: %v", err)
+	}
+	messages, err := teamsMessages(subject, elements)
+	if err != nil {
+		return err
+	}
+
+	for _, contact := range contacts {
+		for _, message := range messages {
+			if err := n.post(contact.Address, message); err != nil {
+				return fmt.Errorf("error sending Teams message: %v", err)
+			}
+		}
+		log.Info("Teams message sent successfully")
+	}
+
+	return nil
+}
+
+// FormatContent returns the JSON Adaptive Card elements of the errors, a facts table per error
+func (n teamsWebhookNotifier) FormatContent(errs []validator.ValidationError) (string, error) {
+	groups := groupErrors(errs, time.Now())
+
+	var elements []cardElement
+	for _, section := range groups.Sections() {
+		elements = append(elements, teamsErrorElements(section.Title, section.Errors)...)
+	}
+	if note := groups.AcknowledgedNote(); note != "" {
+		elements = append(elements, cardElement{Type: "TextBlock", Text: note, Wrap: true})
+	}
+
+	content, err := json.Marshal(elements)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal Teams card: %v", err)
+	}
+
+	return string(content), nil
+}
+
+// teamsErrorElements returns a title followed by one container per error so that an error is never split across cards
+func teamsErrorElements(title string, errs []groupedError) []cardElement {
+	elements := []cardElement{{Type: "TextBlock", Text: title, Weight: "Bolder", Wrap: true}}
+	for _, e := range errs {
+		elements = append(elements, cardElement{
+			Type: "Container",
+			Items: []cardElement{
+				{Type: "TextBlock", Text: e.Summary(), Weight: "Bolder", Wrap: true},
+				{Type: "FactSet", Facts: []cardFact{
+					{Title: "Path", Value: e.Path},
+					{Title: "Actual", Value: e.Actual},
+					{Title: "Expected", Value: e.Expected},
+					{Title: "Rule", Value: e.Rule},
+					{Title: "Fingerprint", Value: e.Fingerprint()},
+				}},
+			},
+		})
+	}
+	return elements
+}
+
+// teamsMessages splits the elements over cards of at most teamsMaxCardBytes,
+// every card starts with a title with the subject
+func teamsMessages(subject string, elements []cardElement) ([]teamsMessage, error) {
+	var cards [][]cardElement
+	var card []cardElement
+	size := 0
+	for _, element := range elements {
+		b, err := json.Marshal(element)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal Teams card: %v", err)
+		}
+		if len(card) > 0 && size+len(b) > teamsMaxCardBytes {
+			cards = append(cards, card)
+			card, size = nil, 0
+		}
+		card = append(card, element)
+		size += len(b)
+	}
+	if len(card) > 0 || len(cards) == 0 {
+		cards = append(cards, card)
+	}
+
+	messages := make([]teamsMessage, 0, len(cards))
+	for i, body := range cards {
+		title := subject
+		if len(cards) > 1 {
+			title = fmt.Sprintf("%s (%d/%d)", subject, i+1, len(cards))
+		}
+		header := cardElement{Type: "TextBlock", Text: title, Weight: "Bolder", Size: "Medium", Wrap: true}
+		messages = append(messages, teamsMessage{
+			Type: "message",
+			Attachments: []teamsAttachment{{
+				ContentType: adaptiveCardContentType,
+				Content: adaptiveCard{
+					Schema:  adaptiveCardSchema,
+					Type:    "AdaptiveCard",
+					Version: adaptiveCardVersion,
+					Body:    append([]cardElement{header}, body...),
+				},
+			}},
+		})
+	}
+	return messages, nil
+}
+
+func (n teamsWebhookNotifier) post(url string, message teamsMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("could not marshal message: %v", err)
+	}
+	log.Debugf("Teams Request:\n%s", body)
+
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected response status %q: %s", resp.Status, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTeamsNotifierDispatch(t *testing.T) {
+	var mu sync.Mutex
+	var messages []teamsMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		// Teams rejects messages larger than 28KB
+		if len(body) > 28*1024 {
+			t.Errorf("expected at most 28KB, got %d bytes", len(body))
+		}
+		var message teamsMessage
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Errorf("invalid message: %v", err)
+		}
+		messages = append(messages, message)
+	}))
+	defer server.Close()
+
+	n := &teamsWebhookNotifier{client: server.Client()}
+	n.SetContactsGetter(func() []Contact {
+		return []Contact{{Address: server.URL}}
+	})
+
+	tests := []struct {
+		name      string
+		errs      int
+		wantCards int
+	}{
+		{name: "single card", errs: 3, wantCards: 1},
+		{name: "chunked", errs: 200, wantCards: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages = nil
+			var errs []validator.ValidationError
+			for i := 0; i < tt.errs; i++ {
+				name := fmt.Sprintf("%d-31-19.docx", i)
+				path := "/Invoices/John Doe/" + name
+				errs = append(errs, validator.ValidationError{
+					Actual:         fmt.Sprintf("%q", name),
+					Expected:       "Something like \"013119-01.docx\" or \"013119-01.pdf\"",
+					AdditionalInfo: fmt.Sprintf("File: %q", path),
+					Rule:           "file-name",
+					Path:           path,
+				})
+			}
+			if err := Dispatch(n, "subject", errs); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(messages) != tt.wantCards {
+				t.Fatalf("expected %d cards, got %d", tt.wantCards, len(messages))
+			}
+			var containers []cardElement
+			for i, message := range messages {
+				if message.Type != "message" || len(message.Attachments) != 1 {
+					t.Fatalf("unexpected message: %+v", message)
+				}
+				attachment := message.Attachments[0]
+				card := attachment.Content
+				if attachment.ContentType != adaptiveCardContentType || card.Schema != adaptiveCardSchema || card.Type != "AdaptiveCard" || card.Version != adaptiveCardVersion {
+					t.Errorf("unexpected card: %+v", attachment)
+				}
+
+				// every card starts with the subject, numbered when there are several
+				title := "subject"
+				if tt.wantCards > 1 {
+					title = fmt.Sprintf("subject (%d/%d)", i+1, tt.wantCards)
+				}
+				if len(card.Body) < 2 || card.Body[0].Text != title {
+					t.Fatalf("expected the title %q, got %+v", title, card.Body)
+				}
+				for _, element := range card.Body[1:] {
+					if element.Type == "Container" {
+						containers = append(containers, element)
+					}
+				}
+			}
+			if !strings.Contains(messages[0].Attachments[0].Content.Body[1].Text, "failed validators") {
+				t.Errorf("expected the errors title, got %+v", messages[0].Attachments[0].Content.Body[1])
+			}
+
+			// the errors are listed once and in order, an error is never split across cards
+			if len(containers) != tt.errs {
+				t.Fatalf("expected %d errors, got %d", tt.errs, len(containers))
+			}
+			for i, container := range containers {
+				facts := container.Items[1].Facts
+				if facts[0].Title != "Path" || facts[0].Value != errs[i].Path {
+					t.Errorf("expected the facts of %q, got %+v", errs[i].Path, facts)
+				}
+			}
+		})
+	}
+}
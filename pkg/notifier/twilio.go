@@ -8,6 +8,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"os"
 	"strings"
+	"time"
 )
 
 const (
@@ -53,6 +54,10 @@ func (n *twilioNotifier) SetContactsGetter(f func() []Contact) {
 	n.contacts = f
 }
 
+func (n twilioNotifier) Contacts() []Contact {
+	return n.contacts()
+}
+
 func (n twilioNotifier) Send(subject string, content string) error {
 	log.Info("Notifying using SMS notifier...")
 	contacts := n.contacts()
@@ -79,11 +84,23 @@ func (n twilioNotifier) Send(subject string, content string) error {
 }
 
 func (n twilioNotifier) FormatContent(errs []validator.ValidationError) (string, error) {
+	groups := groupErrors(errs, time.Now())
+
 	var content string
-	content = "Below is the list of failed validators:\n\n"
+	for _, section := range groups.Sections() {
+		content += formatSMSErrors(section.Title+"\n\n", section.Errors)
+	}
+	if note := groups.AcknowledgedNote(); note != "" {
+		content = fmt.Sprintf("%s%s\n", content, note)
+	}
+	return content, nil
+}
+
+func formatSMSErrors(content string, errs []groupedError) string {
 	for _, e := range errs {
-		content = fmt.Sprintf("%s%s\nActual: %s\nExpected: %s", content, e.AdditionalInfo, e.Actual, e.Expected)
+		content = fmt.Sprintf("%s%s", content, e.Summary())
+		content = fmt.Sprintf("%s\nActual: %s\nExpected: %s\nRule: %s", content, e.Actual, e.Expected, e.Rule)
 		content = fmt.Sprintf("%s\n%s\n", content, strings.Repeat("-", 30))
 	}
-	return content, nil
+	return content
 }
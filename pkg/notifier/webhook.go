@@ -0,0 +1,237 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/stringsx"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	webhookSecretEnv = "WEBHOOK_SECRET"
+
+	// notifierWebhookURLsEnv is "," separated as URLs contain ":"
+	notifierWebhookURLsEnv = "NOTIFIER_WEBHOOK_URLS"
+
+	// WebhookPayloadVersion is incremented on incompatible changes to the webhook payload
+	WebhookPayloadVersion = "v1"
+	// WebhookSignatureHeader is the "sha256=" prefixed hex HMAC-SHA256 of the body keyed with WEBHOOK_SECRET
+	WebhookSignatureHeader = "X-Invoices-Validator-Signature"
+
+	webhookAttempts       = 4
+	defaultWebhookBackoff = time.Second
+)
+
+var defaultWebhookContactsGetter = func() []Contact {
+	urls := os.Getenv(notifierWebhookURLsEnv)
+	contacts := make([]Contact, 0)
+	for _, url := range stringsx.Split(urls, ",") {
+		contacts = append(contacts, Contact{Address: url})
+	}
+
+	return contacts
+}
+
+type signedWebhookNotifier struct {
+	client *http.Client
+	secret []byte
+	root   string
+	// runID is generated once per notifier, the notifiers are configured at the start of every run
+	runID string
+	// backoff is the delay before the first retry, doubled on every retry
+	backoff time.Duration
+
+	contacts func() []Contact
+}
+
+// WebhookPayload is the JSON document posted to the webhooks
+type WebhookPayload struct {
+	Version string `json:"version"`
+	// RunID identifies the run, it is the same for all the payloads posted during a run
+	RunID     string         `json:"runId"`
+	Timestamp time.Time      `json:"timestamp"`
+	Root      string         `json:"root"`
+	Errors    []WebhookError `json:"errors"`
+}
+
+// WebhookError is a ValidationError in the webhook payload
+type WebhookError struct {
+	Fingerprint    string `json:"fingerprint"`
+	Rule           string `json:"rule"`
+	Severity       string `json:"severity"`
+	Path           string `json:"path"`
+	Actual         string `json:"actual"`
+	Expected       string `json:"expected"`
+	AdditionalInfo string `json:"additionalInfo"`
+	Status         string `json:"status,omitempty"`
+	// Since is only set when errors are tracked between runs
+	Since        *time.Time `json:"since,omitempty"`
+	Acknowledged bool       `json:"acknowledged"`
+	AckReason    string     `json:"ackReason,omitempty"`
+}
+
+// NewWebhookNotifier posts the errors as a signed JSON document to the NOTIFIER_WEBHOOK_URLS
+func NewWebhookNotifier() (Notifier, error) {
+	log.Info("Initializing webhook notifier...")
+
+	secret := os.Getenv(webhookSecretEnv)
+	if secret == "" {
+		return nil, fmt.Errorf("%s variable must be set", webhookSecretEnv)
+	}
+
+	notifier, err := newWebhookNotifier(&http.Client{Timeout: 30 * time.Second}, secret, defaultWebhookBackoff)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Webhook notifier initialized successfully")
+
+	return notifier, nil
+}
+
+func newWebhookNotifier(client *http.Client, secret string, backoff time.Duration) (*signedWebhookNotifier, error) {
+	runID, err := newRunID()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate run ID: %v", err)
+	}
+
+	return &signedWebhookNotifier{
+		client:   client,
+		secret:   []byte(secret),
+		runID:    runID,
+		backoff:  backoff,
+		contacts: defaultWebhookContactsGetter,
+	}, nil
+}
+
+func (n *signedWebhookNotifier) SetContactsGetter(f func() []Contact) {
+	n.contacts = f
+}
+
+func (n signedWebhookNotifier) Contacts() []Contact {
+	return n.contacts()
+}
+
+func (n *signedWebhookNotifier) SetRoot(root string) {
+	n.root = root
+}
+
+// Send posts the payload of FormatContent to every webhook, the subject is not part of the payload
+func (n signedWebhookNotifier) Send(subject string, content string) error {
+	log.Info("Notifying using webhook notifier...")
+	contacts := n.contacts()
+	if len(contacts) == 0 {
+		return fmt.Errorf("empty webhook URL list to send to")
+	}
+
+	body := []byte(content)
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	for _, contact := range contacts {
+		if err := n.post(contact.Address, body, signature); err != nil {
+			return fmt.Errorf("error sending webhook to %q: %v", contact.Address, err)
+		}
+		log.Infof("Webhook sent successfully to: %q", contact.Address)
+	}
+
+	return nil
+}
+
+// FormatContent returns the JSON WebhookPayload of the errors
+func (n signedWebhookNotifier) FormatContent(errs []validator.ValidationError) (string, error) {
+	payload := WebhookPayload{
+		Version:   WebhookPayloadVersion,
+		RunID:     n.runID,
+		Timestamp: time.Now().UTC(),
+		Root:      n.root,
+		Errors:    make([]WebhookError, 0, len(errs)),
+	}
+	for _, e := range errs {
+		var since *time.Time
+		if !e.Since.IsZero() {
+			since = &e.Since
+		}
+		payload.Errors = append(payload.Errors, WebhookError{
+			Fingerprint:    e.Fingerprint(),
+			Rule:           e.Rule,
+			Severity:       e.Severity,
+			Path:           e.Path,
+			Actual:         e.Actual,
+			Expected:       e.Expected,
+			AdditionalInfo: e.AdditionalInfo,
+			Status:         e.Status,
+			Since:          since,
+			Acknowledged:   e.Acknowledged,
+			AckReason:      e.AckReason,
+		})
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal webhook payload: %v", err)
+	}
+
+	return string(content), nil
+}
+
+// post sends the body, retrying with an exponential backoff on network errors and 5xx responses
+func (n signedWebhookNotifier) post(url string, body []byte, signature string) error {
+	backoff := n.backoff
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		var retry bool
+		retry, err = n.postOnce(url, body, signature)
+		if err == nil || !retry {
+			return err
+		}
+		if attempt < webhookAttempts {
+			log.Warnf("Webhook attempt %d/%d to %q failed, retrying in %s: %v", attempt, webhookAttempts, url, backoff, err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %v", webhookAttempts, err)
+}
+
+// postOnce returns true if the request failed and can be retried
+func (n signedWebhookNotifier) postOnce(url string, body []byte, signature string) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, signature)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode >= 500, fmt.Errorf("unexpected response status %q: %s", resp.Status, respBody)
+	}
+	return false, nil
+}
+
+func newRunID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/dkoshkin/invoices-validator/pkg/validator"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestWebhookNotifierDispatch(t *testing.T) {
+	var mu sync.Mutex
+	payloads := make(map[string]WebhookPayload)
+	attempts := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get(WebhookSignatureHeader) != want {
+			t.Errorf("expected signature %q, got %q", want, r.Header.Get(WebhookSignatureHeader))
+		}
+
+		// the first attempt of every webhook fails and is retried
+		attempts[r.URL.Path]++
+		if attempts[r.URL.Path] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		payloads[r.URL.Path] = payload
+	}))
+	defer server.Close()
+
+	n, err := newWebhookNotifier(server.Client(), "secret", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n.SetRoot("/Invoices")
+	n.SetContactsGetter(func() []Contact {
+		return []Contact{
+			{Address: server.URL + "/all"},
+			{Address: server.URL + "/john", Folders: []string{"/Invoices/John Doe"}},
+		}
+	})
+
+	errs := []validator.ValidationError{
+		{Rule: "file-name", Path: "/Invoices/John Doe/1-31-19.docx"},
+		{Rule: "file-name", Path: "/Invoices/Jane Doe/1-31-19.docx"},
+	}
+	if err := Dispatch(n, "subject", errs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	all, john := payloads["/all"], payloads["/john"]
+	if len(all.Errors) != 2 || len(john.Errors) != 1 || john.Errors[0].Path != "/Invoices/John Doe/1-31-19.docx" {
+		t.Errorf("unexpected payloads: %+v, %+v", all, john)
+	}
+	// the payloads are formatted separately for every group of contacts, but belong to the same run
+	if all.RunID == "" || all.RunID != john.RunID {
+		t.Errorf("expected the same run ID, got %q and %q", all.RunID, john.RunID)
+	}
+	if all.Version != WebhookPayloadVersion || all.Root != "/Invoices" {
+		t.Errorf("unexpected payload: %+v", all)
+	}
+
+	other, err := newWebhookNotifier(server.Client(), "secret", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other.runID == n.runID {
+		t.Errorf("expected a new run ID for every run, got %q twice", n.runID)
+	}
+}
@@ -0,0 +1,442 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/dkoshkin/invoices-validator/pkg/glob"
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rulesFileEnv = "RULES_FILE"
+
+	AppliesToFolders = "folders"
+	AppliesToFiles   = "files"
+
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	// matchAll is the path pattern of rules that don't specify one
+	matchAll = "**"
+
+	// dateGroup is the name of the regex group capturing the invoice date
+	dateGroup = "date"
+	// sequenceGroup is the name of the regex group capturing the per-day invoice number
+	sequenceGroup = "seq"
+	// defaultDateLayout is the Go time layout of dates captured by dateGroup, ie "013119"
+	defaultDateLayout = "010206"
+)
+
+// httpClient downloads the rules files served over http(s), a stalled server must not block the run
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Rule validates the names of folders or files with paths matching Path
+type Rule struct {
+	Name string `yaml:"name"`
+	// Path is a glob matched against the full path of an entry, ie "/Invoices/**", defaults to all paths
+	Path      string `yaml:"path"`
+	AppliesTo string `yaml:"appliesTo"`
+	Regex     string `yaml:"regex"`
+	// Expected is a human-readable description of what the name should look like
+	Expected string `yaml:"expected"`
+	Severity string `yaml:"severity"`
+	// Extensions allowed for the name, ie [".docx", ".pdf"], when set Regex is matched against the name without its extension
+	Extensions []string `yaml:"extensions"`
+	// DateLayout is the Go time layout of the "date" named group of Regex, defaults to "010206"
+	DateLayout string `yaml:"dateLayout"`
+
+	regex *regexp.Regexp
+}
+
+// Structure describes the expected folder layout below the root folder,
+// ie "/Invoices/<Client First Last>/<files>" or "/Invoices/<Client First Last>/<YYYY>/<files>" with YearFolders
+type Structure struct {
+	// MaxDepth is the deepest level of folders allowed below the root, defaults to 1 or 2 with YearFolders
+	MaxDepth int `yaml:"maxDepth"`
+	// AllowRootFiles allows files directly inside the root folder
+	AllowRootFiles bool `yaml:"allowRootFiles"`
+	// YearFolders requires client folders to only contain folders named YYYY with the files
+	YearFolders bool `yaml:"yearFolders"`
+}
+
+type RuleSet struct {
+	Rules []*Rule `yaml:"rules"`
+	// Structure is only validated when the section is present in the rules file
+	Structure *Structure `yaml:"structure"`
+}
+
+// Default returns the rules used when no rules file is configured
+func Default() *RuleSet {
+	rs := &RuleSet{
+		Rules: []*Rule{
+			{
+				// Do not allow ',' as that likely means its Last, First name
+				Name:      "folder-name",
+				AppliesTo: AppliesToFolders,
+				Regex:     "^[^,]*$",
+				Expected:  "First Last, ie John Doe",
+			},
+			{
+				Name:       "file-name",
+				AppliesTo:  AppliesToFiles,
+				Regex:      `^(?P<date>(0[1-9]|1[0-2])(0[1-9]|1\d|2\d|3[01])(1|2)\d{1})-(?P<seq>\d{2})$`,
+				Extensions: []string{".docx", ".pdf"},
+				Expected:   "Something like \"013119-01.docx\" or \"013119-01.pdf\"",
+			},
+		},
+	}
+	if err := rs.Validate(); err != nil {
+		panic(fmt.Sprintf("invalid default rules: %v", err))
+	}
+
+	return rs
+}
+
+// ConfiguredRules loads the rules from RULES_FILE, falling back to the default rules when it's not set
+func ConfiguredRules() (*RuleSet, error) {
+	rulesFile := os.Getenv(rulesFileEnv)
+	if rulesFile == "" {
+		log.Info("Using default rules")
+		return Default(), nil
+	}
+
+	rulesFile = resolve(rulesFile)
+	log.Infof("Using rules file: %q", rulesFile)
+	return Load(rulesFile)
+}
+
+// resolve returns the path of a relative rules file that doesn't exist in the working directory
+// relative to the directory of the binary instead, ie when the file is deployed next to the Lambda function
+func resolve(file string) string {
+	if isURL(file) || filepath.IsAbs(file) {
+		return file
+	}
+	if _, err := os.Stat(file); err == nil {
+		return file
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return file
+	}
+	candidate := filepath.Join(filepath.Dir(executable), file)
+	if _, err := os.Stat(candidate); err != nil {
+		return file
+	}
+
+	return candidate
+}
+
+func isURL(file string) bool {
+	return strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://")
+}
+
+// Load reads a YAML or JSON rules file, file can either be a local path or an http(s) URL
+// so that the rules can be changed without redeploying the Lambda function
+func Load(file string) (*RuleSet, error) {
+	data, err := read(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read rules file: %v", err)
+	}
+
+	rs := &RuleSet{}
+	if err := yaml.UnmarshalStrict(data, rs); err != nil {
+		return nil, fmt.Errorf("could not parse rules file %q: %v", file, err)
+	}
+	if err := rs.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rules file %q: %v", file, err)
+	}
+
+	return rs, nil
+}
+
+func read(file string) ([]byte, error) {
+	if !isURL(file) {
+		return ioutil.ReadFile(file)
+	}
+
+	resp, err := httpClient.Get(file)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Validate checks all rules are well formed, setting default values and compiling the regular expressions
+func (rs *RuleSet) Validate() error {
+	if len(rs.Rules) == 0 {
+		return fmt.Errorf("at least one rule must be defined")
+	}
+
+	var errs []string
+	if rs.Structure != nil {
+		if err := rs.Structure.validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("structure: %v", err))
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, r := range rs.Rules {
+		if r.Name == "" {
+			errs = append(errs, fmt.Sprintf("rule %d: name must be set", i+1))
+			continue
+		}
+		if names[r.Name] {
+			errs = append(errs, fmt.Sprintf("rule %q: duplicate name", r.Name))
+		}
+		names[r.Name] = true
+
+		if err := r.validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("rule %q: %v", r.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (s *Structure) validate() error {
+	minDepth := 1
+	if s.YearFolders {
+		minDepth = 2
+	}
+
+	switch {
+	case s.MaxDepth == 0:
+		s.MaxDepth = minDepth
+	case s.MaxDepth < minDepth:
+		return fmt.Errorf("maxDepth must be at least %d, got %d", minDepth, s.MaxDepth)
+	}
+
+	return nil
+}
+
+func (r *Rule) validate() error {
+	if r.Path == "" {
+		r.Path = matchAll
+	}
+	if err := glob.Validate(r.Path); err != nil {
+		return fmt.Errorf("invalid path %q: %v", r.Path, err)
+	}
+
+	switch r.AppliesTo {
+	case AppliesToFolders, AppliesToFiles:
+	default:
+		return fmt.Errorf("appliesTo must be one of %q or %q, got %q", AppliesToFolders, AppliesToFiles, r.AppliesTo)
+	}
+
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityError
+	case SeverityError, SeverityWarning:
+	default:
+		return fmt.Errorf("severity must be one of %q or %q, got %q", SeverityError, SeverityWarning, r.Severity)
+	}
+
+	if r.Regex == "" {
+		return fmt.Errorf("regex must be set")
+	}
+	regex, err := regexp.Compile(r.Regex)
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %v", r.Regex, err)
+	}
+	r.regex = regex
+
+	if r.hasGroup(dateGroup) {
+		if r.DateLayout == "" {
+			r.DateLayout = defaultDateLayout
+		}
+	} else if r.DateLayout != "" {
+		return fmt.Errorf("dateLayout is set but regex %q has no %q named group", r.Regex, dateGroup)
+	}
+
+	for i, ext := range r.Extensions {
+		if ext == "" || strings.Contains(ext, "/") {
+			return fmt.Errorf("invalid extension %q", ext)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		r.Extensions[i] = strings.ToLower(ext)
+	}
+
+	if r.Expected == "" {
+		r.Expected = fmt.Sprintf("Name matching %q", r.Regex)
+		if len(r.Extensions) > 0 {
+			r.Expected = fmt.Sprintf("%s with one of the extensions %s", r.Expected, strings.Join(r.Extensions, ", "))
+		}
+	}
+
+	return nil
+}
+
+// For returns the rules that apply to the entry.
+// When several rules match, only the ones with the most specific path are returned
+// so that ie a "/Invoices/Hourly/**" rule overrides a "/Invoices/**" rule for the entries under "/Invoices/Hourly"
+func (rs *RuleSet) For(entry source.Entry) []*Rule {
+	var matched []*Rule
+	var best specificity
+	for _, r := range rs.Rules {
+		if !r.appliesTo(entry) {
+			continue
+		}
+
+		spec := pathSpecificity(r.Path)
+		switch {
+		case len(matched) == 0 || spec.greater(best):
+			matched = []*Rule{r}
+			best = spec
+		case spec == best:
+			matched = append(matched, r)
+		}
+	}
+
+	return matched
+}
+
+// Matches reports whether at least one rule applies to the root folder or the entries below it
+func (rs *RuleSet) Matches(root string) bool {
+	for _, r := range rs.Rules {
+		// patterns were validated when loading the rules
+		if match, _ := glob.MatchBelow(strings.ToLower(r.Path), strings.ToLower(root)); match {
+			return true
+		}
+	}
+	return false
+}
+
+// specificity of a path pattern, patterns with more literal segments are more specific,
+// ties are broken by the number of literal characters
+type specificity struct {
+	segments   int
+	characters int
+}
+
+func (s specificity) greater(other specificity) bool {
+	if s.segments != other.segments {
+		return s.segments > other.segments
+	}
+	return s.characters > other.characters
+}
+
+func pathSpecificity(pattern string) specificity {
+	spec := specificity{}
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if segment == "**" || segment == "" {
+			continue
+		}
+		if !strings.ContainsAny(segment, `*?[\`) {
+			spec.segments++
+		}
+		for _, c := range segment {
+			if !strings.ContainsRune(`*?[]\`, c) {
+				spec.characters++
+			}
+		}
+	}
+
+	return spec
+}
+
+func (r *Rule) appliesTo(entry source.Entry) bool {
+	if entry.IsFolder != (r.AppliesTo == AppliesToFolders) {
+		return false
+	}
+
+	// patterns were validated when loading the rules
+	match, _ := glob.Match(strings.ToLower(r.Path), entry.PathLower)
+	return match
+}
+
+// MatchString reports whether the name satisfies the rule regex and extensions
+func (r *Rule) MatchString(name string) bool {
+	return r.submatch(name) != nil
+}
+
+// submatch returns the regex submatches of the name, or nil if the name doesn't satisfy the rule
+func (r *Rule) submatch(name string) []string {
+	if len(r.Extensions) > 0 {
+		ext := path.Ext(name)
+		allowed := false
+		for _, e := range r.Extensions {
+			if strings.ToLower(ext) == e {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil
+		}
+		name = strings.TrimSuffix(name, ext)
+	}
+
+	return r.regex.FindStringSubmatch(name)
+}
+
+// Date returns the date captured by the "date" named group of the rule regex and its layout,
+// returns false if the rule has no such group or the name doesn't match
+func (r *Rule) Date(name string) (string, string, bool) {
+	if !r.hasGroup(dateGroup) {
+		return "", "", false
+	}
+
+	match := r.submatch(name)
+	if match == nil {
+		return "", "", false
+	}
+
+	return match[r.groupIndex(dateGroup)], r.DateLayout, true
+}
+
+// Sequence returns the date and the per-day invoice number captured by the "date" and "seq" named groups,
+// returns false if the rule doesn't have both groups or the name doesn't match
+func (r *Rule) Sequence(name string) (string, int, bool) {
+	if !r.hasGroup(dateGroup) || !r.hasGroup(sequenceGroup) {
+		return "", 0, false
+	}
+
+	match := r.submatch(name)
+	if match == nil {
+		return "", 0, false
+	}
+
+	seq, err := strconv.Atoi(match[r.groupIndex(sequenceGroup)])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return match[r.groupIndex(dateGroup)], seq, true
+}
+
+func (r *Rule) hasGroup(group string) bool {
+	return r.groupIndex(group) >= 0
+}
+
+// groupIndex returns the index of the named group in the regex submatches, or -1 if there is none
+func (r *Rule) groupIndex(group string) int {
+	for i, name := range r.regex.SubexpNames() {
+		if name == group {
+			return i
+		}
+	}
+	return -1
+}
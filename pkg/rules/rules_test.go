@@ -0,0 +1,227 @@
+package rules
+
+import (
+	"github.com/dkoshkin/invoices-validator/pkg/source"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRulesFile = `
+rules:
+  - name: folder-name
+    appliesTo: folders
+    regex: "^[^,]*$"
+  - name: file-name
+    path: /Invoices/**
+    appliesTo: files
+    regex: '^(?P<date>\d{6})-(?P<seq>\d{2})$'
+    extensions: [docx, .PDF]
+  - name: hourly-file-name
+    path: /Invoices/Hourly/**
+    appliesTo: files
+    severity: warning
+    regex: '^(?P<date>\d{4}-\d{2}-\d{2})$'
+    dateLayout: "2006-01-02"
+structure:
+  yearFolders: true
+`
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := ioutil.WriteFile(file, []byte(testRulesFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rules.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testRulesFile))
+	}))
+	defer server.Close()
+
+	for _, location := range []string{file, server.URL + "/rules.yaml"} {
+		rs, err := Load(location)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(rs.Rules) != 3 {
+			t.Fatalf("expected 3 rules, got %d", len(rs.Rules))
+		}
+		folder, file, hourly := rs.Rules[0], rs.Rules[1], rs.Rules[2]
+		// defaults are set for the missing values
+		if folder.Path != "**" || folder.Severity != SeverityError || folder.Expected != `Name matching "^[^,]*$"` {
+			t.Errorf("unexpected defaults: %+v", folder)
+		}
+		if file.DateLayout != "010206" || !reflect.DeepEqual(file.Extensions, []string{".docx", ".pdf"}) {
+			t.Errorf("unexpected date layout or extensions: %+v", file)
+		}
+		if hourly.Severity != SeverityWarning || hourly.DateLayout != "2006-01-02" {
+			t.Errorf("unexpected rule: %+v", hourly)
+		}
+		if rs.Structure == nil || rs.Structure.MaxDepth != 2 {
+			t.Errorf("expected the structure with the default depth of year folders, got %+v", rs.Structure)
+		}
+	}
+
+	for _, location := range []string{filepath.Join(t.TempDir(), "missing.yaml"), server.URL + "/missing.yaml"} {
+		if _, err := Load(location); err == nil || !strings.Contains(err.Error(), "could not read rules file") {
+			t.Errorf("expected a read error for %q, got %v", location, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []*Rule
+		wantErr string
+	}{
+		{
+			name:    "no rules",
+			wantErr: "at least one rule must be defined",
+		},
+		{
+			name:    "bad regex",
+			rules:   []*Rule{{Name: "file-name", AppliesTo: AppliesToFiles, Regex: "^(01"}},
+			wantErr: `rule "file-name": invalid regex "^(01"`,
+		},
+		{
+			name:    "missing regex",
+			rules:   []*Rule{{Name: "file-name", AppliesTo: AppliesToFiles}},
+			wantErr: `rule "file-name": regex must be set`,
+		},
+		{
+			name:    "date layout without date group",
+			rules:   []*Rule{{Name: "file-name", AppliesTo: AppliesToFiles, Regex: `^\d{6}$`, DateLayout: "010206"}},
+			wantErr: `dateLayout is set but regex "^\\d{6}$" has no "date" named group`,
+		},
+		{
+			name:    "bad path",
+			rules:   []*Rule{{Name: "file-name", Path: "/Invoices/[", AppliesTo: AppliesToFiles, Regex: ".*"}},
+			wantErr: `rule "file-name": invalid path "/Invoices/["`,
+		},
+		{
+			name:    "bad applies to",
+			rules:   []*Rule{{Name: "file-name", AppliesTo: "file", Regex: ".*"}},
+			wantErr: `appliesTo must be one of "folders" or "files", got "file"`,
+		},
+		{
+			name:    "bad severity",
+			rules:   []*Rule{{Name: "file-name", AppliesTo: AppliesToFiles, Regex: ".*", Severity: "info"}},
+			wantErr: `severity must be one of "error" or "warning", got "info"`,
+		},
+		{
+			name:    "bad extension",
+			rules:   []*Rule{{Name: "file-name", AppliesTo: AppliesToFiles, Regex: ".*", Extensions: []string{"docs/x"}}},
+			wantErr: `invalid extension "docs/x"`,
+		},
+		{
+			name: "duplicate and missing names",
+			rules: []*Rule{
+				{Name: "file-name", AppliesTo: AppliesToFiles, Regex: ".*"},
+				{Name: "file-name", AppliesTo: AppliesToFiles, Regex: ".*"},
+				{AppliesTo: AppliesToFiles, Regex: ".*"},
+			},
+			wantErr: `rule "file-name": duplicate name; rule 3: name must be set`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &RuleSet{Rules: tt.rules}
+			err := rs.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRuleGroups(t *testing.T) {
+	rs := &RuleSet{Rules: []*Rule{
+		{Name: "date-and-seq", AppliesTo: AppliesToFiles, Regex: `^(?P<date>\d{6})-(?P<seq>\d{2})$`, Extensions: []string{"docx"}},
+		{Name: "date-only", AppliesTo: AppliesToFiles, Regex: `^(?P<date>\d{6})$`},
+		{Name: "no-groups", AppliesTo: AppliesToFiles, Regex: `^\d{6}-\d{2}$`},
+	}}
+	if err := rs.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dateAndSeq, dateOnly, noGroups := rs.Rules[0], rs.Rules[1], rs.Rules[2]
+
+	tests := []struct {
+		name      string
+		rule      *Rule
+		file      string
+		wantDate  string
+		wantSeq   int
+		wantOK    bool
+		wantSeqOK bool
+	}{
+		{name: "date and seq", rule: dateAndSeq, file: "013119-02.docx", wantDate: "013119", wantSeq: 2, wantOK: true, wantSeqOK: true},
+		{name: "extension is case insensitive", rule: dateAndSeq, file: "013119-02.DOCX", wantDate: "013119", wantSeq: 2, wantOK: true, wantSeqOK: true},
+		{name: "other extension", rule: dateAndSeq, file: "013119-02.pdf"},
+		{name: "no match", rule: dateAndSeq, file: "1-31-19.docx"},
+		{name: "missing seq group", rule: dateOnly, file: "013119", wantDate: "013119", wantOK: true},
+		{name: "missing date group", rule: noGroups, file: "013119-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, layout, ok := tt.rule.Date(tt.file)
+			if ok != tt.wantOK || date != tt.wantDate || (ok && layout != defaultDateLayout) {
+				t.Errorf("expected date %q, got %q, %q, %v", tt.wantDate, date, layout, ok)
+			}
+			date, seq, ok := tt.rule.Sequence(tt.file)
+			if ok != tt.wantSeqOK || (ok && (date != tt.wantDate || seq != tt.wantSeq)) {
+				t.Errorf("expected sequence %q %d, got %q %d, %v", tt.wantDate, tt.wantSeq, date, seq, ok)
+			}
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	rs := &RuleSet{Rules: []*Rule{
+		{Name: "folder-name", AppliesTo: AppliesToFolders, Regex: ".*"},
+		{Name: "file-name", AppliesTo: AppliesToFiles, Regex: ".*"},
+		{Name: "invoices-file-name", Path: "/Invoices/**", AppliesTo: AppliesToFiles, Regex: ".*"},
+		{Name: "invoices-extension", Path: "/Invoices/**", AppliesTo: AppliesToFiles, Regex: ".*"},
+		{Name: "hourly-file-name", Path: "/Invoices/Hourly/**", AppliesTo: AppliesToFiles, Regex: ".*"},
+		{Name: "client-year-file-name", Path: "/Invoices/*/2019/**", AppliesTo: AppliesToFiles, Regex: ".*"},
+	}}
+	if err := rs.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isFolder bool
+		want     []string
+	}{
+		{path: "/invoices/john doe", isFolder: true, want: []string{"folder-name"}},
+		{path: "/archive/013119-01.docx", want: []string{"file-name"}},
+		// rules with the same path all apply
+		{path: "/invoices/john doe/013119-01.docx", want: []string{"invoices-file-name", "invoices-extension"}},
+		// the most specific path wins, paths are matched case insensitively
+		{path: "/invoices/hourly/013119-01.docx", want: []string{"hourly-file-name"}},
+		// literal segments are more specific than wildcards
+		{path: "/invoices/hourly/2019/013119-01.docx", want: []string{"hourly-file-name"}},
+		{path: "/invoices/john doe/2019/013119-01.docx", want: []string{"client-year-file-name"}},
+	}
+
+	for _, tt := range tests {
+		var names []string
+		for _, r := range rs.For(source.Entry{PathLower: tt.path, IsFolder: tt.isFolder}) {
+			names = append(names, r.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("For(%q) = %v, want %v", tt.path, names, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package source
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// lastDownloadCache remembers the content of the last downloaded entry
+// so that several checks of the same file only download it once
+type lastDownloadCache struct {
+	downloader Downloader
+
+	id   string
+	data []byte
+}
+
+// NewLastDownloadCache wraps a Downloader, it is not safe for concurrent use
+func NewLastDownloadCache(downloader Downloader) Downloader {
+	return &lastDownloadCache{downloader: downloader}
+}
+
+func (c *lastDownloadCache) Download(entry Entry) (io.ReadCloser, error) {
+	if c.data == nil || c.id != entry.ID {
+		content, err := c.downloader.Download(entry)
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+
+		data, err := ioutil.ReadAll(content)
+		if err != nil {
+			return nil, err
+		}
+		c.id = entry.ID
+		c.data = data
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(c.data)), nil
+}
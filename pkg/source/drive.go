@@ -0,0 +1,271 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	googleDriveTokenEnv        = "GOOGLE_DRIVE_TOKEN"
+	googleDriveClientIDEnv     = "GOOGLE_DRIVE_CLIENT_ID"
+	googleDriveClientSecretEnv = "GOOGLE_DRIVE_CLIENT_SECRET"
+	googleDriveRefreshTokenEnv = "GOOGLE_DRIVE_REFRESH_TOKEN"
+	googleDriveFolderIDEnv     = "GOOGLE_DRIVE_FOLDER_ID"
+	// googleDriveEndpointEnv overrides the Drive API endpoint, ie to point to a test server
+	googleDriveEndpointEnv = "GOOGLE_DRIVE_ENDPOINT"
+
+	defaultGoogleDriveEndpoint = "https://www.googleapis.com/drive/v3"
+	googleTokenURL             = "https://oauth2.googleapis.com/token"
+
+	googleDriveFolderMimeType = "application/vnd.google-apps.folder"
+	// googleDriveNativeMimeTypePrefix is the prefix of the Google Docs, Sheets, etc. MIME types, they can't be downloaded as is
+	googleDriveNativeMimeTypePrefix = "application/vnd.google-apps."
+	googleDrivePageSize             = 1000
+)
+
+type driveSource struct {
+	client   *http.Client
+	endpoint string
+
+	folderID string
+	name     string
+	root     string
+
+	// native are the IDs of the listed Google Docs, Sheets, etc.
+	native map[string]bool
+}
+
+type driveFile struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	MimeType     string `json:"mimeType"`
+	Size         string `json:"size"`
+	ModifiedTime string `json:"modifiedTime"`
+}
+
+type driveFileList struct {
+	NextPageToken string      `json:"nextPageToken"`
+	Files         []driveFile `json:"files"`
+}
+
+// NewDriveSource lists a Google Drive folder, root is the ID of the folder to list.
+// Authentication uses either a static GOOGLE_DRIVE_TOKEN access token
+// or a GOOGLE_DRIVE_CLIENT_ID, GOOGLE_DRIVE_CLIENT_SECRET and GOOGLE_DRIVE_REFRESH_TOKEN triple
+func NewDriveSource(root string) (Source, error) {
+	log.Info("Initializing Google Drive source...")
+
+	folderID := root
+	if folderID == "" {
+		folderID = os.Getenv(googleDriveFolderIDEnv)
+	}
+	if folderID == "" {
+		return nil, fmt.Errorf("%s variable must be set", googleDriveFolderIDEnv)
+	}
+
+	var tokenSource oauth2.TokenSource
+	if token := os.Getenv(googleDriveTokenEnv); token != "" {
+		tokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	} else {
+		clientID := os.Getenv(googleDriveClientIDEnv)
+		clientSecret := os.Getenv(googleDriveClientSecretEnv)
+		refreshToken := os.Getenv(googleDriveRefreshTokenEnv)
+
+		for env, val := range map[string]string{
+			googleDriveClientIDEnv:     clientID,
+			googleDriveClientSecretEnv: clientSecret,
+			googleDriveRefreshTokenEnv: refreshToken,
+		} {
+			if val == "" {
+				return nil, fmt.Errorf("%s or %s variable must be set", googleDriveTokenEnv, env)
+			}
+		}
+
+		config := &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: googleTokenURL},
+		}
+		tokenSource = config.TokenSource(context.Background(), &oauth2.Token{RefreshToken: refreshToken})
+	}
+
+	endpoint := os.Getenv(googleDriveEndpointEnv)
+	if endpoint == "" {
+		endpoint = defaultGoogleDriveEndpoint
+	}
+
+	source, err := newDriveSource(oauth2.NewClient(context.Background(), tokenSource), endpoint, folderID)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Google Drive source initialized successfully")
+
+	return source, nil
+}
+
+func newDriveSource(client *http.Client, endpoint, folderID string) (*driveSource, error) {
+	source := &driveSource{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		folderID: folderID,
+		native:   make(map[string]bool),
+	}
+
+	// Drive only knows folders by their IDs, use the name of the root folder as the base of all paths
+	folder := driveFile{}
+	query := url.Values{"fields": {"id,name,mimeType"}, "supportsAllDrives": {"true"}}
+	if err := source.get(fmt.Sprintf("/files/%s", url.PathEscape(folderID)), query, &folder); err != nil {
+		return nil, fmt.Errorf("could not get folder %q: %v", folderID, err)
+	}
+	if folder.MimeType != googleDriveFolderMimeType {
+		return nil, fmt.Errorf("%q is not a folder", folderID)
+	}
+	source.name = folder.Name
+	source.root = "/" + escapeDriveName(folder.Name)
+
+	return source, nil
+}
+
+func (s driveSource) Root() string {
+	return s.root
+}
+
+func (s driveSource) List(fn func(entry Entry) error) error {
+	type folder struct {
+		id   string
+		path string
+	}
+
+	if err := fn(driveEntry(driveFile{ID: s.folderID, Name: s.name, MimeType: googleDriveFolderMimeType}, s.root)); err != nil {
+		return err
+	}
+
+	// Drive can only list direct children of a folder, walk the tree breadth first
+	queue := []folder{{id: s.folderID, path: s.root}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		pageToken := ""
+		for {
+			query := url.Values{
+				"q":                         {fmt.Sprintf("'%s' in parents and trashed = false", current.id)},
+				"fields":                    {"nextPageToken,files(id,name,mimeType,size,modifiedTime)"},
+				"pageSize":                  {strconv.Itoa(googleDrivePageSize)},
+				"supportsAllDrives":         {"true"},
+				"includeItemsFromAllDrives": {"true"},
+			}
+			if pageToken != "" {
+				query.Set("pageToken", pageToken)
+			}
+
+			res := driveFileList{}
+			if err := s.get("/files", query, &res); err != nil {
+				return fmt.Errorf("could not list folder %q: %v", current.path, err)
+			}
+
+			log.Debugf("Found %d files/folders in the directory %q", len(res.Files), current.path)
+			for _, f := range res.Files {
+				entry := driveEntry(f, current.path+"/"+escapeDriveName(f.Name))
+				if !entry.IsFolder && strings.HasPrefix(f.MimeType, googleDriveNativeMimeTypePrefix) {
+					s.native[f.ID] = true
+				}
+				if err := fn(entry); err != nil {
+					return err
+				}
+				if entry.IsFolder {
+					queue = append(queue, folder{id: f.ID, path: entry.PathDisplay})
+				}
+			}
+
+			pageToken = res.NextPageToken
+			if pageToken == "" {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s driveSource) Download(entry Entry) (io.ReadCloser, error) {
+	if s.native[entry.ID] {
+		return nil, ErrNotDownloadable
+	}
+
+	query := url.Values{"alt": {"media"}, "supportsAllDrives": {"true"}}
+	resp, err := s.do(fmt.Sprintf("/files/%s", url.PathEscape(entry.ID)), query)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %q: %v", entry.PathDisplay, err)
+	}
+
+	return resp.Body, nil
+}
+
+func (s driveSource) get(p string, query url.Values, out interface{}) error {
+	resp, err := s.do(p, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode response: %v", err)
+	}
+
+	return nil
+}
+
+// do sends a GET request to the Drive API, the caller must close the body of successful responses
+func (s driveSource) do(p string, query url.Values) (*http.Response, error) {
+	resp, err := s.client.Get(fmt.Sprintf("%s%s?%s", s.endpoint, p, query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+
+	return resp, nil
+}
+
+func driveEntry(f driveFile, pathDisplay string) Entry {
+	entry := Entry{
+		ID:          f.ID,
+		Name:        f.Name,
+		PathLower:   strings.ToLower(pathDisplay),
+		PathDisplay: pathDisplay,
+		IsFolder:    f.MimeType == googleDriveFolderMimeType,
+	}
+	if size, err := strconv.ParseUint(f.Size, 10, 64); err == nil {
+		entry.Size = size
+	}
+	if modified, err := time.Parse(time.RFC3339, f.ModifiedTime); err == nil {
+		entry.Modified = modified
+	}
+
+	return entry
+}
+
+// driveNameReplacer escapes the "/" allowed in Drive names so that a name is always a single path element
+var driveNameReplacer = strings.NewReplacer("%", "%25", "/", "%2F")
+
+// escapeDriveName returns the name as a single path element, "." and ".." are escaped as they are valid Drive names
+func escapeDriveName(name string) string {
+	switch name {
+	case ".", "..":
+		return strings.Repeat("%2E", len(name))
+	}
+	return driveNameReplacer.Replace(name)
+}
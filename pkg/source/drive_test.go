@@ -0,0 +1,161 @@
+package source
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeDrive serves the files of a Drive tree, children are listed two per page
+type fakeDrive struct {
+	files    map[string]driveFile
+	children map[string][]string
+	contents map[string]string
+}
+
+func (d fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if r.URL.Path == "/files" {
+		parent := strings.TrimSuffix(strings.TrimPrefix(query.Get("q"), "'"), "' in parents and trashed = false")
+		children := d.children[parent]
+		start := 0
+		if token := query.Get("pageToken"); token != "" {
+			start = len(token)
+		}
+		end := start + 2
+		res := driveFileList{}
+		if end < len(children) {
+			res.NextPageToken = strings.Repeat("x", end)
+		} else {
+			end = len(children)
+		}
+		for _, id := range children[start:end] {
+			res.Files = append(res.Files, d.files[id])
+		}
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+
+	f, ok := d.files[strings.TrimPrefix(r.URL.Path, "/files/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if query.Get("alt") == "media" {
+		// Google Docs, Sheets, etc. can only be exported
+		if strings.HasPrefix(f.MimeType, "application/vnd.google-apps.") {
+			http.Error(w, "fileNotDownloadable", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(d.contents[f.ID]))
+		return
+	}
+	json.NewEncoder(w).Encode(f)
+}
+
+func TestDriveSource(t *testing.T) {
+	drive := fakeDrive{
+		files: map[string]driveFile{
+			"root": {ID: "root", Name: "Invoices", MimeType: googleDriveFolderMimeType},
+			"john": {ID: "john", Name: "John Doe", MimeType: googleDriveFolderMimeType},
+			"acme": {ID: "acme", Name: "ACME/Corp", MimeType: googleDriveFolderMimeType},
+			"dots": {ID: "dots", Name: "..", MimeType: googleDriveFolderMimeType},
+			"inv1": {ID: "inv1", Name: "013119-01.docx", Size: "12", ModifiedTime: "2019-01-31T10:00:00Z"},
+			"inv2": {ID: "inv2", Name: "01/31/19.docx", Size: "7"},
+			"inv3": {ID: "inv3", Name: "100% paid.pdf"},
+			"inv4": {ID: "inv4", Name: "020119-01.docx"},
+			"inv5": {ID: "inv5", Name: "020219-01.docx"},
+			"doc1": {ID: "doc1", Name: "020319-01.docx", MimeType: "application/vnd.google-apps.document"},
+		},
+		children: map[string][]string{
+			"root": {"john", "acme", "dots"},
+			"john": {"inv1", "inv2", "doc1"},
+			"acme": {"inv3", "inv4", "inv5"},
+		},
+		contents: map[string]string{"inv1": "invoice"},
+	}
+	server := httptest.NewServer(drive)
+	defer server.Close()
+
+	source, err := newDriveSource(server.Client(), server.URL+"/", "root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.Root() != "/Invoices" {
+		t.Errorf("expected root %q, got %q", "/Invoices", source.Root())
+	}
+
+	entries := make(map[string]Entry)
+	var paths []string
+	err = source.List(func(entry Entry) error {
+		entries[entry.PathDisplay] = entry
+		paths = append(paths, entry.PathDisplay)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantPaths := []string{
+		"/Invoices",
+		"/Invoices/John Doe",
+		"/Invoices/ACME%2FCorp",
+		"/Invoices/%2E%2E",
+		"/Invoices/John Doe/013119-01.docx",
+		"/Invoices/John Doe/01%2F31%2F19.docx",
+		"/Invoices/John Doe/020319-01.docx",
+		"/Invoices/ACME%2FCorp/100%25 paid.pdf",
+		"/Invoices/ACME%2FCorp/020119-01.docx",
+		"/Invoices/ACME%2FCorp/020219-01.docx",
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("expected paths:\n%v\ngot:\n%v", wantPaths, paths)
+	}
+
+	// the names are the Drive names, only the paths are escaped
+	for path, want := range map[string]Entry{
+		"/Invoices":             {ID: "root", Name: "Invoices", PathLower: "/invoices", PathDisplay: "/Invoices", IsFolder: true},
+		"/Invoices/ACME%2FCorp": {ID: "acme", Name: "ACME/Corp", PathLower: "/invoices/acme%2fcorp", PathDisplay: "/Invoices/ACME%2FCorp", IsFolder: true},
+		"/Invoices/John Doe/01%2F31%2F19.docx": {
+			ID: "inv2", Name: "01/31/19.docx", PathLower: "/invoices/john doe/01%2f31%2f19.docx",
+			PathDisplay: "/Invoices/John Doe/01%2F31%2F19.docx", Size: 7,
+		},
+	} {
+		if got := entries[path]; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected entry %+v, got %+v", want, got)
+		}
+	}
+	if modified := entries["/Invoices/John Doe/013119-01.docx"].Modified; modified.Format("2006-01-02") != "2019-01-31" {
+		t.Errorf("unexpected modified time %v", modified)
+	}
+
+	body, err := source.Download(entries["/Invoices/John Doe/013119-01.docx"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer body.Close()
+	if content, _ := ioutil.ReadAll(body); string(content) != "invoice" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	// Google Docs are listed to validate their names, but have no content to check
+	if _, err := source.Download(entries["/Invoices/John Doe/020319-01.docx"]); err != ErrNotDownloadable {
+		t.Errorf("expected ErrNotDownloadable, got %v", err)
+	}
+}
+
+func TestNewDriveSourceNotAFolder(t *testing.T) {
+	server := httptest.NewServer(fakeDrive{files: map[string]driveFile{"inv": {ID: "inv", Name: "013119-01.docx"}}})
+	defer server.Close()
+
+	if _, err := newDriveSource(server.Client(), server.URL, "inv"); err == nil {
+		t.Error("expected an error for a file")
+	}
+	if _, err := newDriveSource(server.Client(), server.URL, "missing"); err == nil {
+		t.Error("expected an error for a missing folder")
+	}
+}
@@ -0,0 +1,168 @@
+package source
+
+import (
+	"fmt"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/files"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path"
+)
+
+const (
+	dropboxTokenEnv = "DROPBOX_TOKEN"
+	dropboxPathEnv  = "DROPBOX_PATH"
+)
+
+type dropboxSource struct {
+	client files.Client
+	path   string
+}
+
+func NewDropboxSource(root string) (Source, error) {
+	log.Info("Initializing Dropbox source...")
+	source := &dropboxSource{}
+
+	dropboxToken := os.Getenv(dropboxTokenEnv)
+	dropboxPath := root
+	if dropboxPath == "" {
+		dropboxPath = os.Getenv(dropboxPathEnv)
+	}
+
+	for env, val := range map[string]string{
+		dropboxTokenEnv: dropboxToken,
+		dropboxPathEnv:  dropboxPath,
+	} {
+		if val == "" {
+			return nil, fmt.Errorf("%s variable must be set", env)
+		}
+	}
+
+	config := dropbox.Config{
+		Token: dropboxToken,
+		//LogLevel: dropbox.LogDebug, // if needed, set the desired logging level. Default is off
+	}
+	source.client = files.New(config)
+	source.path = dropboxPath
+	log.Info("Dropbox source initialized successfully")
+
+	return source, nil
+}
+
+func (s dropboxSource) Root() string {
+	return s.path
+}
+
+func (s dropboxSource) List(fn func(entry Entry) error) error {
+	_, err := s.ListChanges("", fn)
+	return err
+}
+
+func (s dropboxSource) ListChanges(cursor string, fn func(entry Entry) error) (string, error) {
+	hasMore := true
+	for hasMore {
+		// first get the parent folder and a cursor,
+		// then use the cursor to get remaining files and folders
+		var res *files.ListFolderResult
+		var err error
+		if cursor == "" {
+			in := &files.ListFolderArg{
+				Path:      s.path,
+				Recursive: true,
+			}
+			res, err = s.client.ListFolder(in)
+		} else {
+			in := &files.ListFolderContinueArg{
+				Cursor: cursor,
+			}
+			res, err = s.client.ListFolderContinue(in)
+			if isCursorReset(err) {
+				// Dropbox expires cursors from time to time, the only option is to start over
+				log.Warn("Dropbox cursor expired, listing all files/folders")
+				cursor = ""
+				continue
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not list folders: %v", err)
+		}
+		cursor = res.Cursor
+		hasMore = res.HasMore
+
+		log.Debugf("Found %d files/folders in the directory", len(res.Entries))
+		for _, metadata := range res.Entries {
+			entry, ok := dropboxEntry(metadata)
+			if !ok {
+				continue
+			}
+			if err := fn(entry); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return cursor, nil
+}
+
+func (s dropboxSource) Download(entry Entry) (io.ReadCloser, error) {
+	in := &files.DownloadArg{
+		Path: entry.PathLower,
+	}
+	_, content, err := s.client.Download(in)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %q: %v", entry.PathDisplay, err)
+	}
+
+	return content, nil
+}
+
+func (s dropboxSource) Rename(entry Entry, name string) error {
+	// Dropbox never overwrites an existing entry when moving, autorename is off so a conflict is returned as an error instead
+	in := files.NewRelocationArg(entry.PathDisplay, path.Join(path.Dir(entry.PathDisplay), name))
+	if _, err := s.client.MoveV2(in); err != nil {
+		return fmt.Errorf("could not move %q: %v", entry.PathDisplay, err)
+	}
+
+	return nil
+}
+
+// isCursorReset returns true if the error is due to an expired cursor
+func isCursorReset(err error) bool {
+	apiErr, ok := err.(files.ListFolderContinueAPIError)
+	return ok && apiErr.EndpointError != nil && apiErr.EndpointError.Tag == files.ListFolderContinueErrorReset
+}
+
+// dropboxEntry converts Dropbox metadata to an Entry,
+// returns false for unknown metadata types
+func dropboxEntry(metadata files.IsMetadata) (Entry, bool) {
+	switch m := metadata.(type) {
+	case *files.DeletedMetadata:
+		// Dropbox doesn't say whether a deleted entry was a file or a folder
+		return Entry{
+			Name:        m.Name,
+			PathLower:   m.PathLower,
+			PathDisplay: m.PathDisplay,
+			Deleted:     true,
+		}, true
+	case *files.FolderMetadata:
+		return Entry{
+			ID:          m.Id,
+			Name:        m.Name,
+			PathLower:   m.PathLower,
+			PathDisplay: m.PathDisplay,
+			IsFolder:    true,
+		}, true
+	case *files.FileMetadata:
+		return Entry{
+			ID:          m.Id,
+			Name:        m.Name,
+			PathLower:   m.PathLower,
+			PathDisplay: m.PathDisplay,
+			Size:        m.Size,
+			Modified:    m.ServerModified,
+		}, true
+	}
+
+	return Entry{}, false
+}
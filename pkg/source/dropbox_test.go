@@ -0,0 +1,94 @@
+package source
+
+import (
+	"errors"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox"
+	"github.com/dropbox/dropbox-sdk-go-unofficial/dropbox/files"
+	"reflect"
+	"testing"
+)
+
+func resetError(tag string) error {
+	return files.ListFolderContinueAPIError{
+		APIError:      dropbox.APIError{ErrorSummary: tag + "/"},
+		EndpointError: &files.ListFolderContinueError{Tagged: dropbox.Tagged{Tag: tag}},
+	}
+}
+
+func TestIsCursorReset(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "reset", err: resetError(files.ListFolderContinueErrorReset), want: true},
+		{name: "path", err: resetError(files.ListFolderContinueErrorPath)},
+		{name: "no endpoint error", err: files.ListFolderContinueAPIError{}},
+		{name: "other error", err: errors.New("reset")},
+		{name: "no error"},
+	}
+
+	for _, tt := range tests {
+		if got := isCursorReset(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+// fakeDropboxClient lists the entries from scratch and fails to continue from any cursor with continueErr
+type fakeDropboxClient struct {
+	files.Client
+	entries     []files.IsMetadata
+	continueErr error
+
+	listed    int
+	continued []string
+}
+
+func (c *fakeDropboxClient) ListFolder(arg *files.ListFolderArg) (*files.ListFolderResult, error) {
+	c.listed++
+	return &files.ListFolderResult{Entries: c.entries, Cursor: "new"}, nil
+}
+
+func (c *fakeDropboxClient) ListFolderContinue(arg *files.ListFolderContinueArg) (*files.ListFolderResult, error) {
+	c.continued = append(c.continued, arg.Cursor)
+	return nil, c.continueErr
+}
+
+func TestDropboxListChangesCursorReset(t *testing.T) {
+	file := &files.FileMetadata{Metadata: files.Metadata{Name: "013119-01.docx", PathLower: "/invoices/013119-01.docx", PathDisplay: "/Invoices/013119-01.docx"}, Id: "id:1"}
+	deleted := &files.DeletedMetadata{Metadata: files.Metadata{Name: "John Doe", PathLower: "/invoices/john doe", PathDisplay: "/Invoices/John Doe"}}
+
+	client := &fakeDropboxClient{
+		entries:     []files.IsMetadata{file, deleted},
+		continueErr: resetError(files.ListFolderContinueErrorReset),
+	}
+	source := dropboxSource{client: client, path: "/Invoices"}
+
+	var entries []Entry
+	cursor, err := source.ListChanges("expired", func(entry Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// an expired cursor lists all entries again
+	if cursor != "new" || client.listed != 1 || !reflect.DeepEqual(client.continued, []string{"expired"}) {
+		t.Errorf("expected a listing from scratch, got cursor %q, %d listings, continued from %v", cursor, client.listed, client.continued)
+	}
+	want := []Entry{
+		{ID: "id:1", Name: "013119-01.docx", PathLower: "/invoices/013119-01.docx", PathDisplay: "/Invoices/013119-01.docx"},
+		{Name: "John Doe", PathLower: "/invoices/john doe", PathDisplay: "/Invoices/John Doe", Deleted: true},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("expected entries:\n%+v\ngot:\n%+v", want, entries)
+	}
+
+	// other errors are returned
+	client.continueErr = resetError(files.ListFolderContinueErrorPath)
+	if _, err := source.ListChanges("cursor", func(entry Entry) error { return nil }); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,106 @@
+package source
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	localPathEnv = "LOCAL_PATH"
+)
+
+// localSource walks a directory on disk,
+// entry paths are reported relative to the parent of the root directory
+// so that "/home/user/Invoices/John Doe" becomes "/Invoices/John Doe" same as in Dropbox
+type localSource struct {
+	dir  string
+	root string
+}
+
+func NewLocalSource(root string) (Source, error) {
+	log.Info("Initializing local source...")
+	source := &localSource{}
+
+	if root == "" {
+		root = os.Getenv(localPathEnv)
+	}
+	if root == "" {
+		return nil, fmt.Errorf("%s variable must be set", localPathEnv)
+	}
+
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine absolute path of %q: %v", root, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %q: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%q is not a directory", dir)
+	}
+
+	source.dir = dir
+	source.root = path.Join("/", filepath.Base(dir))
+	log.Infof("Local source initialized successfully for directory %q", dir)
+
+	return source, nil
+}
+
+func (s localSource) Root() string {
+	return s.root
+}
+
+func (s localSource) List(fn func(entry Entry) error) error {
+	return filepath.Walk(s.dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("could not walk %q: %v", p, err)
+		}
+
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		pathDisplay := path.Join(s.root, filepath.ToSlash(rel))
+
+		entry := Entry{
+			ID:          p,
+			Name:        path.Base(pathDisplay),
+			PathLower:   strings.ToLower(pathDisplay),
+			PathDisplay: pathDisplay,
+			IsFolder:    info.IsDir(),
+			Modified:    info.ModTime(),
+		}
+		if !info.IsDir() {
+			entry.Size = uint64(info.Size())
+		}
+
+		return fn(entry)
+	})
+}
+
+func (s localSource) Download(entry Entry) (io.ReadCloser, error) {
+	// the ID of local entries is the path on disk
+	return os.Open(entry.ID)
+}
+
+func (s localSource) Rename(entry Entry, name string) error {
+	newPath := filepath.Join(filepath.Dir(entry.ID), name)
+	// os.Rename replaces existing files, a case-only rename on a case-insensitive filesystem finds the entry itself
+	if info, err := os.Lstat(newPath); err == nil {
+		old, err := os.Lstat(entry.ID)
+		if err != nil {
+			return err
+		}
+		if !os.SameFile(info, old) {
+			return fmt.Errorf("%q already exists", newPath)
+		}
+	}
+
+	return os.Rename(entry.ID, newPath)
+}
@@ -0,0 +1,173 @@
+package source
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/dkoshkin/invoices-validator/pkg/awsx"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	s3BucketEnv = "S3_BUCKET"
+	s3PrefixEnv = "S3_PREFIX"
+	// s3EndpointEnv overrides the S3 endpoint, ie to use a MinIO server
+	s3EndpointEnv = "S3_ENDPOINT"
+)
+
+// s3Source lists objects under a key prefix,
+// S3 has no folders so every "/" separated key prefix is reported as a folder
+type s3Source struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Source lists the objects of S3_BUCKET, root is the key prefix to list.
+// The AWS session is created with awsx.NewSession
+func NewS3Source(root string) (Source, error) {
+	log.Info("Initializing S3 source...")
+
+	bucket := os.Getenv(s3BucketEnv)
+	if bucket == "" {
+		return nil, fmt.Errorf("%s variable must be set", s3BucketEnv)
+	}
+
+	prefix := root
+	if prefix == "" {
+		prefix = os.Getenv(s3PrefixEnv)
+	}
+
+	config := aws.NewConfig()
+	if endpoint := os.Getenv(s3EndpointEnv); endpoint != "" {
+		// S3 compatible servers generally don't support virtual-hosted-style bucket addressing
+		config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+	sess, err := awsx.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	source := newS3Source(s3.New(sess), bucket, prefix)
+	log.Infof("S3 source initialized successfully for bucket %q", bucket)
+
+	return source, nil
+}
+
+func newS3Source(client s3iface.S3API, bucket, prefix string) *s3Source {
+	return &s3Source{
+		client: client,
+		bucket: bucket,
+		prefix: strings.Trim(prefix, "/"),
+	}
+}
+
+func (s s3Source) Root() string {
+	return path.Join("/", s.prefix)
+}
+
+func (s s3Source) List(fn func(entry Entry) error) error {
+	// folders that were already reported, keyed by their display path
+	folders := make(map[string]bool)
+	emitFolder := func(pathDisplay string) error {
+		if folders[pathDisplay] {
+			return nil
+		}
+		folders[pathDisplay] = true
+		return fn(s3FolderEntry(pathDisplay))
+	}
+
+	root := s.Root()
+	if err := emitFolder(root); err != nil {
+		return err
+	}
+
+	prefix := ""
+	if s.prefix != "" {
+		prefix = s.prefix + "/"
+	}
+
+	hasMore := true
+	var continuationToken *string
+	for hasMore {
+		in := &s3.ListObjectsV2Input{
+			Bucket:            aws.String(s.bucket),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: continuationToken,
+		}
+		res, err := s.client.ListObjectsV2(in)
+		if err != nil {
+			return fmt.Errorf("could not list objects: %v", err)
+		}
+		continuationToken = res.NextContinuationToken
+		hasMore = aws.BoolValue(res.IsTruncated)
+
+		log.Debugf("Found %d objects in the bucket", len(res.Contents))
+		for _, object := range res.Contents {
+			key := aws.StringValue(object.Key)
+			pathDisplay := path.Join("/", key)
+
+			// report all parent "folders" before the object itself
+			var parents []string
+			for dir := path.Dir(pathDisplay); dir != root && dir != "/"; dir = path.Dir(dir) {
+				parents = append([]string{dir}, parents...)
+			}
+			for _, dir := range parents {
+				if err := emitFolder(dir); err != nil {
+					return err
+				}
+			}
+
+			// zero byte objects ending with a "/" are folder placeholders created by most S3 clients
+			if strings.HasSuffix(key, "/") {
+				if err := emitFolder(pathDisplay); err != nil {
+					return err
+				}
+				continue
+			}
+
+			entry := Entry{
+				ID:          key,
+				Name:        path.Base(pathDisplay),
+				PathLower:   strings.ToLower(pathDisplay),
+				PathDisplay: pathDisplay,
+				Size:        uint64(aws.Int64Value(object.Size)),
+				Modified:    aws.TimeValue(object.LastModified),
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s s3Source) Download(entry Entry) (io.ReadCloser, error) {
+	in := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		// the ID of S3 entries is the object key
+		Key: aws.String(entry.ID),
+	}
+	res, err := s.client.GetObject(in)
+	if err != nil {
+		return nil, fmt.Errorf("could not download %q: %v", entry.PathDisplay, err)
+	}
+
+	return res.Body, nil
+}
+
+func s3FolderEntry(pathDisplay string) Entry {
+	return Entry{
+		ID:          strings.TrimPrefix(pathDisplay, "/") + "/",
+		Name:        path.Base(pathDisplay),
+		PathLower:   strings.ToLower(pathDisplay),
+		PathDisplay: pathDisplay,
+		IsFolder:    true,
+	}
+}
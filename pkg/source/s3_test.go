@@ -0,0 +1,190 @@
+package source
+
+import (
+	"encoding/xml"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeS3 serves the objects of a bucket with the path-style addressing of MinIO, objects are listed two per page
+type fakeS3 struct {
+	t       *testing.T
+	bucket  string
+	objects map[string]string
+}
+
+type fakeS3Object struct {
+	Key          string
+	LastModified time.Time
+	Size         int
+}
+
+type fakeS3ListResult struct {
+	XMLName               xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string
+	Prefix                string
+	KeyCount              int
+	IsTruncated           bool
+	NextContinuationToken string `xml:",omitempty"`
+	Contents              []fakeS3Object
+}
+
+func (s fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	if r.URL.Path == "/"+s.bucket {
+		query := r.URL.Query()
+		if query.Get("list-type") != "2" {
+			s.t.Errorf("expected a ListObjectsV2 request, got %q", r.URL)
+		}
+		var keys []string
+		for key := range s.objects {
+			if strings.HasPrefix(key, query.Get("prefix")) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		start, _ := strconv.Atoi(query.Get("continuation-token"))
+		res := fakeS3ListResult{Name: s.bucket, Prefix: query.Get("prefix")}
+		for i := start; i < len(keys) && i < start+2; i++ {
+			res.Contents = append(res.Contents, fakeS3Object{
+				Key:          keys[i],
+				LastModified: time.Date(2019, 1, 31, 10, 0, 0, 0, time.UTC),
+				Size:         len(s.objects[keys[i]]),
+			})
+		}
+		res.KeyCount = len(res.Contents)
+		if start+2 < len(keys) {
+			res.IsTruncated = true
+			res.NextContinuationToken = strconv.Itoa(start + 2)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(res)
+		return
+	}
+
+	content, ok := s.objects[strings.TrimPrefix(r.URL.Path, "/"+s.bucket+"/")]
+	if !ok {
+		http.Error(w, "NoSuchKey", http.StatusNotFound)
+		return
+	}
+	w.Write([]byte(content))
+}
+
+func TestS3Source(t *testing.T) {
+	server := httptest.NewServer(fakeS3{t: t, bucket: "invoices", objects: map[string]string{
+		"Invoices/":                                  "",
+		"Invoices/John Doe/013119-01.docx":           "invoice",
+		"Invoices/John Doe/2019/":                    "",
+		"Invoices/Jane Doe/2019/020119-01.docx":      "other invoice",
+		"Invoices/Jane Doe/2019/Paid/020219-01.docx": "",
+		"Other/013119-01.docx":                       "not listed",
+	}})
+	defer server.Close()
+
+	t.Setenv("S3_BUCKET", "invoices")
+	t.Setenv("S3_ENDPOINT", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio123")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	source, err := NewS3Source("/Invoices/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.Root() != "/Invoices" {
+		t.Errorf("expected root %q, got %q", "/Invoices", source.Root())
+	}
+
+	entries := make(map[string]Entry)
+	var paths []string
+	err = source.List(func(entry Entry) error {
+		entries[entry.PathDisplay] = entry
+		paths = append(paths, entry.PathDisplay)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the folders are reported once, before their content
+	wantPaths := []string{
+		"/Invoices",
+		"/Invoices/Jane Doe",
+		"/Invoices/Jane Doe/2019",
+		"/Invoices/Jane Doe/2019/020119-01.docx",
+		"/Invoices/Jane Doe/2019/Paid",
+		"/Invoices/Jane Doe/2019/Paid/020219-01.docx",
+		"/Invoices/John Doe",
+		"/Invoices/John Doe/013119-01.docx",
+		"/Invoices/John Doe/2019",
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("expected paths:\n%v\ngot:\n%v", wantPaths, paths)
+	}
+
+	file := Entry{
+		ID:          "Invoices/John Doe/013119-01.docx",
+		Name:        "013119-01.docx",
+		PathLower:   "/invoices/john doe/013119-01.docx",
+		PathDisplay: "/Invoices/John Doe/013119-01.docx",
+		Size:        7,
+		Modified:    time.Date(2019, 1, 31, 10, 0, 0, 0, time.UTC),
+	}
+	if got := entries[file.PathDisplay]; !reflect.DeepEqual(got, file) {
+		t.Errorf("expected entry %+v, got %+v", file, got)
+	}
+	folder := Entry{ID: "Invoices/Jane Doe/", Name: "Jane Doe", PathLower: "/invoices/jane doe", PathDisplay: "/Invoices/Jane Doe", IsFolder: true}
+	if got := entries[folder.PathDisplay]; !reflect.DeepEqual(got, folder) {
+		t.Errorf("expected entry %+v, got %+v", folder, got)
+	}
+
+	body, err := source.(Downloader).Download(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer body.Close()
+	if content, _ := ioutil.ReadAll(body); string(content) != "invoice" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	if _, err := source.(Downloader).Download(Entry{ID: "Invoices/missing.docx"}); err == nil {
+		t.Error("expected an error for a missing object")
+	}
+}
+
+func TestNewS3SourceSharedConfig(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config")
+	if err := ioutil.WriteFile(config, []byte("[profile invoices]\nregion = eu-west-3\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("S3_BUCKET", "invoices")
+	t.Setenv("AWS_CONFIG_FILE", config)
+	t.Setenv("AWS_PROFILE", "invoices")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_SDK_LOAD_CONFIG", "")
+
+	source, err := NewS3Source("Invoices")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if region := aws.StringValue(source.(*s3Source).client.(*s3.S3).Config.Region); region != "eu-west-3" {
+		t.Errorf("expected the region of the profile, got %q", region)
+	}
+}
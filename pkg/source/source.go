@@ -0,0 +1,87 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	sourceEnv = "SOURCE"
+
+	dropboxSourceName = "dropbox"
+	localSourceName   = "local"
+	driveSourceName   = "drive"
+	s3SourceName      = "s3"
+)
+
+// Entry is a provider-neutral representation of a file or folder found in the invoices tree
+type Entry struct {
+	ID   string
+	Name string
+	// PathLower is the lowercased full path, always starting with a '/'
+	PathLower string
+	// PathDisplay is the cased full path to be used for display purposes
+	PathDisplay string
+	IsFolder    bool
+	// Deleted is only set for entries returned by IncrementalSource.ListChanges
+	Deleted  bool
+	Size     uint64
+	Modified time.Time
+}
+
+// Source lists the invoices tree of a storage provider
+type Source interface {
+	// Root returns the display path of the folder being listed
+	Root() string
+	// List walks the root folder recursively calling fn for every file and folder found,
+	// listing stops at the first error returned by fn
+	List(fn func(entry Entry) error) error
+}
+
+// IncrementalSource is implemented by sources that can list only the entries changed since a previous listing
+type IncrementalSource interface {
+	Source
+	// ListChanges calls fn for every entry added, changed or deleted since the listing that returned the cursor,
+	// or for every entry when cursor is empty. It returns the cursor to list the next changes from
+	ListChanges(cursor string, fn func(entry Entry) error) (string, error)
+}
+
+// Renamer is implemented by sources that can rename files and folders
+type Renamer interface {
+	// Rename renames the entry to name inside the same folder, it fails if an entry with the new name already exists
+	Rename(entry Entry, name string) error
+}
+
+// Downloader is implemented by sources that can read the content of the files they list
+type Downloader interface {
+	// Download returns the content of a file entry, the caller must close it.
+	// It returns ErrNotDownloadable for entries without content to download
+	Download(entry Entry) (io.ReadCloser, error)
+}
+
+// ErrNotDownloadable is returned by Downloader.Download for files that only exist in the storage provider, ie Google Docs
+var ErrNotDownloadable = errors.New("file has no downloadable content")
+
+// ConfiguredSource returns the source with the given name, falling back to the SOURCE variable and then to Dropbox,
+// root overrides the source specific path variable when set
+func ConfiguredSource(name, root string) (Source, error) {
+	if name == "" {
+		name = os.Getenv(sourceEnv)
+	}
+
+	switch name {
+	case "", dropboxSourceName:
+		return NewDropboxSource(root)
+	case localSourceName:
+		return NewLocalSource(root)
+	case driveSourceName:
+		return NewDriveSource(root)
+	case s3SourceName:
+		return NewS3Source(root)
+	}
+
+	return nil, fmt.Errorf("unknown source %q", name)
+}
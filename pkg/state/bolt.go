@@ -0,0 +1,67 @@
+package state
+
+import (
+	"fmt"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+var boltBucket = []byte("state")
+
+// boltStore keeps the values in a BoltDB file,
+// the file is only opened for the duration of a call so that multiple runs can share it
+type boltStore struct {
+	path string
+}
+
+func NewBoltStore(path string) (Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("state file path must be set")
+	}
+
+	return &boltStore{path: path}, nil
+}
+
+func (s *boltStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.update(func(tx *bbolt.Tx) error {
+		// the value is only valid during the transaction
+		if v := tx.Bucket(boltBucket).Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read %q: %v", key, err)
+	}
+
+	return value, nil
+}
+
+func (s *boltStore) Put(key string, value []byte) error {
+	err := s.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), value)
+	})
+	if err != nil {
+		return fmt.Errorf("could not write %q: %v", key, err)
+	}
+
+	return nil
+}
+
+// update opens the database and runs fn in a read-write transaction,
+// a read-write transaction is also used for reads to create the bucket on first use
+func (s *boltStore) update(fn func(tx *bbolt.Tx) error) error {
+	db, err := bbolt.Open(s.path, 0600, &bbolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return fmt.Errorf("could not open state database %q: %v", s.path, err)
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(boltBucket); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
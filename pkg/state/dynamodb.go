@@ -0,0 +1,95 @@
+package state
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/dkoshkin/invoices-validator/pkg/awsx"
+	log "github.com/sirupsen/logrus"
+	"os"
+)
+
+const (
+	dynamoDBTableEnv = "DYNAMODB_TABLE"
+	// dynamoDBEndpointEnv overrides the DynamoDB endpoint, ie to use DynamoDB Local
+	dynamoDBEndpointEnv = "DYNAMODB_ENDPOINT"
+
+	// the table must have a string partition key named "key"
+	dynamoDBKeyAttribute   = "key"
+	dynamoDBValueAttribute = "value"
+)
+
+// dynamoDBStore keeps every value in its own item of an existing table
+type dynamoDBStore struct {
+	client dynamodbiface.DynamoDBAPI
+	table  string
+}
+
+// NewDynamoDBStore stores the values in DYNAMODB_TABLE.
+// The AWS session is created with awsx.NewSession
+func NewDynamoDBStore() (Store, error) {
+	log.Info("Initializing DynamoDB state store...")
+	table := os.Getenv(dynamoDBTableEnv)
+	if table == "" {
+		return nil, fmt.Errorf("%s variable must be set", dynamoDBTableEnv)
+	}
+
+	config := aws.NewConfig()
+	if endpoint := os.Getenv(dynamoDBEndpointEnv); endpoint != "" {
+		config = config.WithEndpoint(endpoint)
+	}
+	sess, err := awsx.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("DynamoDB state store initialized successfully for table %q", table)
+
+	return &dynamoDBStore{
+		client: dynamodb.New(sess),
+		table:  table,
+	}, nil
+}
+
+func (s *dynamoDBStore) Get(key string) ([]byte, error) {
+	in := &dynamodb.GetItemInput{
+		TableName: aws.String(s.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			dynamoDBKeyAttribute: {S: aws.String(key)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+	out, err := s.client.GetItem(in)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %q: %v", key, err)
+	}
+
+	if out.Item == nil {
+		return nil, nil
+	}
+	// DynamoDB doesn't allow empty binary values, so they are stored without the attribute
+	value, ok := out.Item[dynamoDBValueAttribute]
+	if !ok || value.B == nil {
+		return []byte{}, nil
+	}
+
+	return value.B, nil
+}
+
+func (s *dynamoDBStore) Put(key string, value []byte) error {
+	item := map[string]*dynamodb.AttributeValue{
+		dynamoDBKeyAttribute: {S: aws.String(key)},
+	}
+	if len(value) > 0 {
+		item[dynamoDBValueAttribute] = &dynamodb.AttributeValue{B: value}
+	}
+	in := &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	}
+	if _, err := s.client.PutItem(in); err != nil {
+		return fmt.Errorf("could not write %q: %v", key, err)
+	}
+
+	return nil
+}
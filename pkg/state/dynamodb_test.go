@@ -0,0 +1,124 @@
+package state
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDynamoDB stands in for DynamoDB Local, it only implements GetItem and PutItem on tables with a "key" partition key
+type fakeDynamoDB struct {
+	t      *testing.T
+	tables map[string]map[string]json.RawMessage
+
+	mu sync.Mutex
+}
+
+type fakeDynamoDBRequest struct {
+	TableName      string
+	Key            map[string]map[string]string
+	Item           map[string]map[string]string
+	ConsistentRead bool
+}
+
+func (d *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var req fakeDynamoDBRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		d.t.Errorf("invalid request: %v", err)
+	}
+	items, ok := d.tables[req.TableName]
+	if !ok {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Requested resource not found"}`))
+		return
+	}
+
+	switch target := r.Header.Get("X-Amz-Target"); target {
+	case "DynamoDB_20120810.GetItem":
+		if !req.ConsistentRead {
+			d.t.Error("expected a consistent read")
+		}
+		item, ok := items[req.Key["key"]["S"]]
+		if !ok {
+			w.Write([]byte(`{}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]json.RawMessage{"Item": item})
+	case "DynamoDB_20120810.PutItem":
+		item, _ := json.Marshal(req.Item)
+		items[req.Item["key"]["S"]] = item
+		w.Write([]byte(`{}`))
+	default:
+		d.t.Errorf("unexpected operation %q", target)
+	}
+}
+
+func newTestDynamoDBStore(t *testing.T, table string) (*dynamoDBStore, *fakeDynamoDB) {
+	fake := &fakeDynamoDB{t: t, tables: map[string]map[string]json.RawMessage{"invoices-state": {}}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	t.Setenv(dynamoDBTableEnv, table)
+	t.Setenv(dynamoDBEndpointEnv, server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "local")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "local")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	store, err := NewDynamoDBStore()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return store.(*dynamoDBStore), fake
+}
+
+func TestDynamoDBStore(t *testing.T) {
+	store, fake := newTestDynamoDBStore(t, "invoices-state")
+
+	value, err := store.Get("history")
+	if err != nil || value != nil {
+		t.Errorf("expected nil for a missing key, got %q, %v", value, err)
+	}
+
+	for _, want := range []string{`{"runs":1}`, `{"runs":2}`} {
+		if err := store.Put("history", []byte(want)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		value, err := store.Get("history")
+		if err != nil || string(value) != want {
+			t.Errorf("expected %q, got %q, %v", want, value, err)
+		}
+	}
+	if len(fake.tables["invoices-state"]) != 1 {
+		t.Errorf("expected the value to be overwritten, got %d items", len(fake.tables["invoices-state"]))
+	}
+
+	// empty values are stored without the value attribute
+	if err := store.Put("history", []byte{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item := string(fake.tables["invoices-state"]["history"]); strings.Contains(item, "value") {
+		t.Errorf("expected no value attribute, got %s", item)
+	}
+	value, err = store.Get("history")
+	if err != nil || value == nil || len(value) != 0 {
+		t.Errorf("expected an empty value, got %q, %v", value, err)
+	}
+}
+
+func TestDynamoDBStoreMissingTable(t *testing.T) {
+	store, _ := newTestDynamoDBStore(t, "missing")
+
+	if _, err := store.Get("history"); err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("expected a ResourceNotFoundException, got %v", err)
+	}
+	if err := store.Put("history", []byte("{}")); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,89 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// fileStore keeps all values in a single JSON file,
+// values are stored as strings to keep the file readable and must be valid UTF-8
+type fileStore struct {
+	path string
+}
+
+func NewFileStore(path string) (Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("state file path must be set")
+	}
+
+	return &fileStore{path: path}, nil
+}
+
+func (s *fileStore) Get(key string) ([]byte, error) {
+	values, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := values[key]
+	if !ok {
+		return nil, nil
+	}
+
+	return []byte(value), nil
+}
+
+func (s *fileStore) Put(key string, value []byte) error {
+	values, err := s.read()
+	if err != nil {
+		return err
+	}
+	values[key] = string(value)
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode state: %v", err)
+	}
+
+	// write to a temporary file first so that a failed write doesn't corrupt the state
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return fmt.Errorf("could not write state file: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write state file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write state file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write state file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *fileStore) read() (map[string]string, error) {
+	values := make(map[string]string)
+
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read state file: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("could not decode state file %q: %v", s.path, err)
+	}
+
+	return values, nil
+}
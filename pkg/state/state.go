@@ -0,0 +1,49 @@
+package state
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	stateStoreEnv = "STATE_STORE"
+	stateFileEnv  = "STATE_FILE"
+
+	fileStoreName     = "file"
+	boltStoreName     = "bolt"
+	dynamoDBStoreName = "dynamodb"
+)
+
+// Store persists values between runs
+type Store interface {
+	// Get returns the value of the key, or nil if it was never set
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+}
+
+// ConfiguredStore returns the store selected with STATE_STORE, path overrides STATE_FILE for the file and bolt stores.
+// When STATE_STORE is not set a file store is used if a path is set, otherwise nil is returned and no state is kept between runs
+func ConfiguredStore(name, path string) (Store, error) {
+	if name == "" {
+		name = os.Getenv(stateStoreEnv)
+	}
+	if path == "" {
+		path = os.Getenv(stateFileEnv)
+	}
+
+	switch name {
+	case "":
+		if path == "" {
+			return nil, nil
+		}
+		return NewFileStore(path)
+	case fileStoreName:
+		return NewFileStore(path)
+	case boltStoreName:
+		return NewBoltStore(path)
+	case dynamoDBStoreName:
+		return NewDynamoDBStore()
+	}
+
+	return nil, fmt.Errorf("unknown state store %q", name)
+}
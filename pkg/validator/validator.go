@@ -1,9 +1,42 @@
 package validator
 
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// StatusNew is an error that was not reported by the previous run
+	StatusNew = "new"
+	// StatusOutstanding is an error that was already reported by a previous run
+	StatusOutstanding = "outstanding"
+	// StatusResolved is an error reported by the previous run that is now fixed
+	StatusResolved = "resolved"
+)
+
 type ValidationError struct {
 	Actual         string
 	Expected       string
 	AdditionalInfo string
+	// Severity is either "error" or "warning"
+	Severity string
+	// Rule is the name of the rule that failed
+	Rule string
+	// Path is the file or folder that failed the rule,
+	// errors about several files in a folder use the folder joined with the common name prefix, ie "/Invoices/John Doe/013119*"
+	Path string
+	// Status is one of StatusNew, StatusOutstanding or StatusResolved, empty when errors are not tracked between runs
+	Status string
+	// Since is when the error was first reported, zero when errors are not tracked between runs
+	Since time.Time
+	// Acknowledged errors are expected and not notified about
+	Acknowledged bool
+	AckReason    string
+}
+
+// Fingerprint identifies the same error across runs
+func (e ValidationError) Fingerprint() string {
+	return e.Rule + ":" + strings.ToLower(e.Path)
 }
 
 type validatable interface {